@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/pflag"
+)
+
+// archiveOptions configure the archive of all generator commands.
+type archiveOptions struct {
+	comment           string
+	commentFile       string
+	entryComment      string
+	entryCommentFile  string
+	maxComments       bool
+	fakeEOCDComment   bool
+	decoyEOCD         string
+	decoyZip64        bool
+	prepend           []byte
+	prependUnadjusted bool
+	prefixFile        string
+	forceZip64        bool
+	zip64Extra        string
+	utf8Flag          string
+	unicodeExtra      bool
+	workers           int
+}
+
+func (o *archiveOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.comment, "comment", "", "", "archive comment")
+	flags.StringVarP(&o.commentFile, "comment-file", "", "", "file with the archive comment")
+	flags.StringVarP(&o.entryComment, "entry-comment", "", "", "comment of every entry in the central directory")
+	flags.StringVarP(&o.entryCommentFile, "entry-comment-file", "", "", "file with the comment of every entry")
+	flags.BoolVarP(&o.maxComments, "max-comments", "", false, "pad the archive and entry comments to the maximum length of 65535 bytes")
+	flags.BoolVarP(&o.fakeEOCDComment, "fake-eocd-comment", "", false, "embed a fake end of central directory record in the archive and entry comments")
+	flags.StringVarP(&o.decoyEOCD, "decoy-eocd", "", "", "placement of a decoy end of central directory record (comment|trailing)")
+	flags.BoolVarP(&o.decoyZip64, "decoy-zip64", "", false, "precede the decoy end of central directory record by zip64 records")
+	flags.BytesHexVarP(&o.prepend, "prepend", "", nil, "bytes written before the first local header")
+	flags.BoolVarP(&o.prependUnadjusted, "prepend-unadjusted", "", false, "keep offsets relative to the end of the prepended bytes")
+	flags.StringVarP(&o.prefixFile, "prefix-file", "", "", "carrier file (png, pdf, elf, shell stub, ...) written before the archive")
+	flags.BoolVarP(&o.forceZip64, "force-zip64", "", false, "use zip64 for every entry and the end records")
+	flags.StringVarP(&o.utf8Flag, "utf8", "", "auto", "language encoding flag for utf-8 names (auto|always|never)")
+	flags.BoolVarP(&o.unicodeExtra, "unicode-extra", "", false, "add unicode path and comment extra fields to non-ascii entries")
+	flags.IntVarP(&o.workers, "workers", "", 1, "number of goroutines that deflate non-repetitive kernels")
+	flags.StringVarP(&o.zip64Extra, "zip64-extra", "", "standard", "layout of the zip64 extra blocks (standard|reversed|partial|none)")
+}
+
+// options returns the zipbomb options.
+func (o *archiveOptions) options() (func(*zipbomb.Options), error) {
+	var decoyEOCD zipbomb.DecoyEOCD
+
+	switch o.decoyEOCD {
+	case "":
+		decoyEOCD = zipbomb.NoDecoyEOCD
+	case "comment":
+		decoyEOCD = zipbomb.DecoyEOCDComment
+	case "trailing":
+		decoyEOCD = zipbomb.DecoyEOCDTrailing
+	default:
+		return nil, fmt.Errorf("unknown decoy eocd placement %q", o.decoyEOCD)
+	}
+
+	zip64Extra, err := zipbomb.ParseZip64Extra(o.zip64Extra)
+	if err != nil {
+		return nil, err
+	}
+
+	utf8Flag, err := zipbomb.ParseUTF8Flag(o.utf8Flag)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.workers < 1 {
+		return nil, fmt.Errorf("invalid number of workers %d", o.workers)
+	}
+
+	if o.maxComments && decoyEOCD == zipbomb.DecoyEOCDComment {
+		return nil, errors.New("--max-comments leaves no room for --decoy-eocd comment")
+	}
+
+	comment, err := o.commentText(o.comment, o.commentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	entryComment, err := o.commentText(o.entryComment, o.entryCommentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := o.prepend
+
+	if o.prefixFile != "" {
+		carrier, err := os.ReadFile(o.prefixFile)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix = append(carrier, prefix...)
+	}
+
+	return func(opts *zipbomb.Options) {
+		opts.EOCDComment = comment
+		opts.EntryComment = entryComment
+		opts.DecoyEOCD = decoyEOCD
+		opts.DecoyZip64 = o.decoyZip64
+		opts.Prefix = prefix
+		opts.UnadjustedPrefixOffsets = o.prependUnadjusted
+		opts.ForceZip64 = o.forceZip64
+		opts.Zip64Extra = zip64Extra
+		opts.UTF8Flag = utf8Flag
+		opts.UnicodeExtra = o.unicodeExtra
+		opts.Workers = o.workers
+	}, nil
+}
+
+// commentText returns the comment from text or the file, with the fake end
+// of central directory record and the padding applied.
+func (o *archiveOptions) commentText(text, file string) (string, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		text = string(b)
+	}
+
+	if o.fakeEOCDComment {
+		text = zipbomb.FakeEOCDComment(text)
+	}
+
+	if o.maxComments {
+		text = zipbomb.PadComment(text)
+	}
+
+	if len(text) > zipbomb.MaxCommentLength {
+		return "", fmt.Errorf("comment of %d bytes exceeds %d bytes", len(text), zipbomb.MaxCommentLength)
+	}
+
+	return text, nil
+}
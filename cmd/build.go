@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/recipe"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb/v8"
+)
+
+type buildOptions struct {
+	recipeFile string
+	verify     bool
+}
+
+func newBuildCmd(rootOpts *rootOptions) *cobra.Command {
+	opts := &buildOptions{}
+	cmd := &cobra.Command{
+		Use:           "build",
+		Short:         "Create an archive from a recipe",
+		Long:          "Create an archive that combines overlap, no-overlap, zip-slip and plain file segments described in a yaml recipe",
+		Example:       `- zipbomb build -f recipe.yaml --verify`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rcp, err := recipe.LoadFile(opts.recipeFile)
+			if err != nil {
+				return err
+			}
+
+			creatingStart := time.Now()
+
+			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
+
+			archive, err := createOutput(rootOpts.output, opts.verify)
+			if err != nil {
+				return err
+			}
+
+			defer archive.Close()
+
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), int64(rcp.NumFiles()))
+
+			zbomb, err := rcp.Make(archive, func(o *recipe.MakeOptions) {
+				o.OnFileCreateHook = func(name string) {
+					bar.Increment()
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			p.Wait()
+
+			creatingEnd := time.Now()
+
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
+
+			if opts.verify {
+				if err = verifyArchive(rep, archive.Path(), true); err != nil {
+					return err
+				}
+			}
+
+			return printReport(rootOpts, rep)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.recipeFile, "file", "f", "", "recipe file (yaml)")
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive")
+
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
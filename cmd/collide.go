@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/filename"
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb/v8"
+)
+
+type collideOptions struct {
+	numFiles         int
+	name             string
+	collision        string
+	benign           string
+	archive          archiveOptions
+	verify           bool
+	decoys           []string
+	kernel           kernelOptions
+	compressionLevel int
+	method           string
+	encryption       encryptionOptions
+}
+
+func newCollideCmd(rootOpts *rootOptions) *cobra.Command {
+	opts := &collideOptions{}
+	cmd := &cobra.Command{
+		Use:   "collide",
+		Short: "Create an archive with colliding entry names",
+		Long:  "Create an archive with duplicate names, names that differ only by case or Unicode normalization, or file/directory clashes. The first entry is benign, the last one carries the payload",
+		Example: `- zipbomb collide --name readme.txt
+- zipbomb collide --collision case -N 4
+- zipbomb collide --collision file-dir --name a`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method, err := zipbomb.ParseMethod(opts.method)
+			if err != nil {
+				return err
+			}
+
+			collision, err := filename.ParseCollision(opts.collision)
+			if err != nil {
+				return err
+			}
+
+			encryption, err := opts.encryption.options()
+			if err != nil {
+				return err
+			}
+
+			archiveOpts, err := opts.archive.options()
+			if err != nil {
+				return err
+			}
+
+			kb, err := opts.kernel.kernel()
+			if err != nil {
+				return err
+			}
+
+			creatingStart := time.Now()
+
+			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
+
+			archive, err := createOutput(rootOpts.output, opts.verify)
+			if err != nil {
+				return err
+			}
+
+			defer archive.Close()
+
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), int64(opts.numFiles))
+
+			zbomb, err := zipbomb.New(archive, archiveOpts)
+			if err != nil {
+				return err
+			}
+
+			if err = addDecoys(zbomb, opts.decoys); err != nil {
+				return err
+			}
+
+			if err = zbomb.AddCollision(kb, opts.numFiles, func(o *zipbomb.CollisionOptions) {
+				o.FilenameGen = filename.NewCollisionGenerator(opts.name, collision)
+				o.CompressionLevel = opts.compressionLevel
+				o.Method = method
+				o.Benign = []byte(opts.benign)
+				o.EncryptionOptions = encryption
+				o.OnFileCreateHook = func(name string) {
+					bar.Increment()
+				}
+			}); err != nil {
+				return err
+			}
+
+			if err = zbomb.Close(); err != nil {
+				return err
+			}
+
+			p.Wait()
+
+			creatingEnd := time.Now()
+
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
+
+			if opts.verify {
+				if err = verifyArchive(rep, archive.Path(), true); err != nil {
+					return err
+				}
+			}
+
+			return printReport(rootOpts, rep)
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.numFiles, "num-files", "N", 2, "number of colliding files")
+	cmd.Flags().StringVarP(&opts.name, "name", "", "readme.txt", "name the entries collide with")
+	cmd.Flags().StringVarP(&opts.collision, "collision", "", string(filename.CollisionDuplicate), "kind of collision (duplicate|case|normalization|file-dir)")
+	cmd.Flags().StringVarP(&opts.benign, "benign", "", "harmless", "content of all entries but the last")
+	opts.archive.addFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive")
+	cmd.Flags().StringSliceVarP(&opts.decoys, "decoy", "", nil, "ordinary file added in front of the bomb")
+	opts.kernel.addFlags(cmd.Flags(), []byte{'B'}, 1024*1024)
+	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9], ignored for repeated --kernel-bytes unless 0 or -2")
+	cmd.Flags().StringVarP(&opts.method, "method", "M", "deflate", "compression method (deflate|bzip2|store)")
+	opts.encryption.addFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+)
+
+// addDecoys adds the files as ordinary entries named after their base name.
+func addDecoys(zbomb *zipbomb.ZipBomb, paths []string) error {
+	for _, path := range paths {
+		if err := addDecoy(zbomb, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addDecoy(zbomb *zipbomb.ZipBomb, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	finfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return zbomb.AddFile(filepath.Base(path), f, func(o *zipbomb.FileOptions) {
+		o.FileMode = finfo.Mode()
+		o.Modified = finfo.ModTime()
+	})
+}
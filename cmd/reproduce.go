@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"os"
+	"time"
 
 	"github.com/hupe1980/zipbomb/pkg/reproduce"
 	"github.com/spf13/cobra"
@@ -14,14 +14,27 @@ func newSelfReproduceCmd(rootOpts *rootOptions) *cobra.Command {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			archive, err := os.Create(rootOpts.output)
+			creatingStart := time.Now()
+
+			archive, err := createOutput(rootOpts.output, false)
 			if err != nil {
 				return err
 			}
 
 			defer archive.Close()
 
-			return reproduce.Make(archive)
+			w := newReportWriter(archive)
+
+			if err = reproduce.Make(w); err != nil {
+				return err
+			}
+
+			creatingEnd := time.Now()
+
+			// the only entry is the archive itself
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), w.Report(1, w.count))
+
+			return printReport(rootOpts, rep)
 		},
 	}
 
@@ -3,9 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"time"
+	"sort"
 
-	"github.com/hupe1980/zipbomb/pkg/zipbomb"
 	"github.com/spf13/cobra"
 )
 
@@ -14,13 +13,14 @@ func Execute(version string) {
 
 	rootCmd := newRootCmd(version)
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
 type rootOptions struct {
-	output string
+	output       string
+	outputFormat string
 }
 
 func newRootCmd(version string) *cobra.Command {
@@ -30,11 +30,20 @@ func newRootCmd(version string) *cobra.Command {
 		Version:       version,
 		Short:         "Tool that creates different types of zip bombs",
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(opts.outputFormat)
+		},
 	}
 
-	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", "bomb.zip", "output filename")
+	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", "bomb.zip", "output filename (- for stdout)")
+	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output-format", "", outputFormatText, "output format of the report (text|json|yaml)")
 
 	cmd.AddCommand(
+		newBuildCmd(opts),
+		newCollideCmd(opts),
+		newDeepNestingCmd(opts),
+		newDifferentialCmd(opts),
+		newManyFilesCmd(opts),
 		newNoOverlapCmd(opts),
 		newOverlapCmd(opts),
 		newSelfReproduceCmd(opts),
@@ -44,6 +53,17 @@ func newRootCmd(version string) *cobra.Command {
 	return cmd
 }
 
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 func printInfof(format string, a ...any) {
 	fmt.Fprintf(os.Stderr, "[i] %s\n", fmt.Sprintf(format, a...))
 }
@@ -59,20 +79,3 @@ func printLogo() {
 |_____|_|  _|_____|___|_|_|_|___|
 	|_|                      `, "\n\n")
 }
-
-func printStats(name string, duration time.Duration, zbomb *zipbomb.ZipBomb) error {
-	finfo, err := os.Stat(name)
-	if err != nil {
-		return err
-	}
-
-	emptyLine()
-	printInfof("Archive: %s", name)
-	printInfof("Zip64: %t", zbomb.IsZip64())
-	printInfof("Comcompressed size: %d %s", finfo.Size()/1024, "KB")
-	printInfof("Uncomcompressed size: %d %s", zbomb.UncompressedSize()/(1024*1024), "MB")
-	printInfof("Ratio: %.2f", float64(zbomb.UncompressedSize())/float64(finfo.Size()))
-	printInfof("Creating time elapsed: %s\n", duration)
-
-	return nil
-}
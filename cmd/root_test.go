@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestRootCmdHelp(t *testing.T) {
@@ -25,3 +33,138 @@ func TestRootCmdVersion(t *testing.T) {
 	assert.NoError(t, cmd.Execute())
 	assert.Equal(t, "zipbomb version 1.2.3\n", b.String())
 }
+
+func TestKernelOptions(t *testing.T) {
+	o := kernelOptions{bytes: []byte{'B'}, repeats: -1}
+
+	_, err := o.kernel()
+	assert.Error(t, err)
+
+	o.repeats = 10
+
+	kb, err := o.kernel()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), kb.Len())
+}
+
+func TestExtraOptions(t *testing.T) {
+	o := extraOptions{owner: -1, group: -1, mtime: "2021-03-04T05:06:08Z"}
+
+	_, err := o.options()
+	assert.NoError(t, err)
+
+	o.atime = "1960-01-01T00:00:00Z"
+
+	_, err = o.options()
+	assert.Error(t, err)
+}
+
+func TestReportWriter(t *testing.T) {
+	var b bytes.Buffer
+
+	w := newReportWriter(&b)
+
+	_, err := w.Write([]byte("zipbomb"))
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("zipbomb"))
+
+	r := w.Report(1, 14)
+	assert.Equal(t, "zipbomb", b.String())
+	assert.Equal(t, hex.EncodeToString(sum[:]), r.SHA256)
+	assert.Equal(t, int64(7), r.CompressedSize)
+	assert.Equal(t, int64(14), r.UncompressedSize)
+	assert.Equal(t, 2.0, r.Ratio)
+	assert.Equal(t, 1, r.Entries)
+}
+
+func TestReportOutput(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "bomb.zip")
+
+		stdout, _ := execute(t, "no-overlap", "-N", "3", "--password", "secret", "--output-format", "json", "-o", name)
+
+		rep := report{}
+		assert.NoError(t, json.Unmarshal(stdout, &rep))
+
+		b, err := os.ReadFile(name)
+		assert.NoError(t, err)
+
+		sum := sha256.Sum256(b)
+
+		assert.Equal(t, name, rep.Archive)
+		assert.Equal(t, hex.EncodeToString(sum[:]), rep.SHA256)
+		assert.Equal(t, int64(len(b)), rep.CompressedSize)
+		assert.Equal(t, 3, rep.Entries)
+		assert.Equal(t, "3", rep.Parameters["num-files"])
+		assert.Equal(t, redacted, rep.Parameters["password"])
+	})
+
+	t.Run("YAML to stdout", func(t *testing.T) {
+		stdout, stderr := execute(t, "no-overlap", "-N", "3", "--output-format", "yaml", "-o", "-")
+
+		// the archive is written to stdout, the report to stderr
+		r, err := zip.NewReader(bytes.NewReader(stdout), int64(len(stdout)))
+		assert.NoError(t, err)
+		assert.Len(t, r.File, 3)
+
+		i := bytes.Index(stderr, []byte("archive: stdout"))
+		assert.GreaterOrEqual(t, i, 0)
+
+		rep := report{}
+		assert.NoError(t, yaml.Unmarshal(stderr[i:], &rep))
+
+		sum := sha256.Sum256(stdout)
+
+		assert.Equal(t, hex.EncodeToString(sum[:]), rep.SHA256)
+		assert.Equal(t, int64(len(stdout)), rep.CompressedSize)
+		assert.Equal(t, 3, rep.Entries)
+		assert.Equal(t, "", rep.Parameters["password"])
+	})
+}
+
+// execute runs the root command with args and returns what it wrote to
+// stdout and stderr.
+func execute(t *testing.T, args ...string) ([]byte, []byte) {
+	t.Helper()
+
+	capture := func(f **os.File) (func() []byte, error) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+
+		orig := *f
+		*f = w
+
+		out := make(chan []byte)
+
+		go func() {
+			b, _ := io.ReadAll(r)
+			out <- b
+		}()
+
+		return func() []byte {
+			*f = orig
+
+			w.Close()
+
+			return <-out
+		}, nil
+	}
+
+	stdout, err := capture(&os.Stdout)
+	assert.NoError(t, err)
+
+	stderr, err := capture(&os.Stderr)
+	assert.NoError(t, err)
+
+	cmd := newRootCmd("")
+	cmd.SetArgs(args)
+	err = cmd.Execute()
+
+	errOut, out := stderr(), stdout()
+	assert.NoError(t, err, string(errOut))
+
+	return out, errOut
+}
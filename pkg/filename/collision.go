@@ -0,0 +1,116 @@
+package filename
+
+import (
+	"fmt"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Collision is the way the names of a CollisionGenerator collide.
+type Collision string
+
+const (
+	// CollisionDuplicate repeats the name.
+	CollisionDuplicate Collision = "duplicate"
+
+	// CollisionCase varies the case of the letters of the name.
+	CollisionCase Collision = "case"
+
+	// CollisionNormalization alternates between the NFC and the NFD form of
+	// the name.
+	CollisionNormalization Collision = "normalization"
+
+	// CollisionFileDir uses the name as file first and as directory of the
+	// following names.
+	CollisionFileDir Collision = "file-dir"
+)
+
+// Collisions are all collisions.
+var Collisions = []Collision{CollisionDuplicate, CollisionCase, CollisionNormalization, CollisionFileDir}
+
+// ParseCollision returns the collision with the given name.
+func ParseCollision(name string) (Collision, error) {
+	for _, c := range Collisions {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown collision %q", name)
+}
+
+type CollisionGenerator struct {
+	name      string
+	collision Collision
+	children  Generator
+}
+
+// NewCollisionGenerator returns a generator whose names collide with name on
+// case-insensitive or normalizing file systems, or in extractors that
+// overwrite existing files. The name of index 0 is name itself, except for
+// normalization collisions, which use the NFC form of name and prefix names
+// without decomposable letters by one.
+func NewCollisionGenerator(name string, collision Collision) Generator {
+	g := &CollisionGenerator{
+		name:      name,
+		collision: collision,
+		children:  NewDefaultGenerator(DefaultAlphabet, ""),
+	}
+
+	if collision == CollisionNormalization {
+		g.name = norm.NFC.String(name)
+
+		if norm.NFD.String(g.name) == g.name {
+			g.name = "\u00e9" + g.name
+		}
+	}
+
+	return g
+}
+
+func (g *CollisionGenerator) Generate(i int) string {
+	switch g.collision {
+	case CollisionCase:
+		return toggleCase(g.name, i)
+	case CollisionNormalization:
+		if i%2 == 1 {
+			return norm.NFD.String(g.name)
+		}
+
+		return g.name
+	case CollisionFileDir:
+		if i == 0 {
+			return g.name
+		}
+
+		return g.name + "/" + g.children.Generate(i-1)
+	default:
+		return g.name
+	}
+}
+
+// toggleCase toggles the case of the letters of name that correspond to the
+// set bits of i.
+func toggleCase(name string, i int) string {
+	runes := []rune(name)
+	bit := 0
+
+	for j, r := range runes {
+		if !unicode.IsLetter(r) || unicode.ToUpper(r) == unicode.ToLower(r) {
+			continue
+		}
+
+		if i>>bit&1 == 1 {
+			if unicode.IsUpper(r) {
+				runes[j] = unicode.ToLower(r)
+			} else {
+				runes[j] = unicode.ToUpper(r)
+			}
+		}
+
+		bit++
+	}
+
+	return string(runes)
+}
@@ -44,3 +44,23 @@ func (g *DefaultGenerator) Generate(i int) string {
 
 	return string(letters)
 }
+
+// Len returns the length of the name with index i without generating it.
+func (g *DefaultGenerator) Len(i int) int {
+	n := 0
+
+	for {
+		n++
+
+		i = i/len(g.alphabet) - 1
+		if i < 0 {
+			break
+		}
+	}
+
+	if g.extension != "" {
+		return n + 1 + len(g.extension)
+	}
+
+	return n
+}
@@ -1,6 +1,7 @@
 package filename
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,3 +30,167 @@ func TestDefaultGenerator(t *testing.T) {
 		assert.Equal(t, "0.pdf", name)
 	})
 }
+
+func TestCollisionGenerator(t *testing.T) {
+	t.Run("Duplicate", func(t *testing.T) {
+		gen := NewCollisionGenerator("readme.txt", CollisionDuplicate)
+		assert.Equal(t, "readme.txt", gen.Generate(0))
+		assert.Equal(t, "readme.txt", gen.Generate(7))
+	})
+
+	t.Run("Case", func(t *testing.T) {
+		gen := NewCollisionGenerator("ab.c", CollisionCase)
+		assert.Equal(t, "ab.c", gen.Generate(0))
+		assert.Equal(t, "Ab.c", gen.Generate(1))
+		assert.Equal(t, "aB.c", gen.Generate(2))
+		assert.Equal(t, "AB.C", gen.Generate(7))
+	})
+
+	t.Run("Normalization", func(t *testing.T) {
+		gen := NewCollisionGenerator("caf\u00e9", CollisionNormalization)
+		assert.Equal(t, "caf\u00e9", gen.Generate(0))
+		assert.Equal(t, "cafe\u0301", gen.Generate(1))
+
+		gen = NewCollisionGenerator("readme", CollisionNormalization)
+		assert.Equal(t, "\u00e9readme", gen.Generate(0))
+		assert.Equal(t, "e\u0301readme", gen.Generate(1))
+
+		// uppercase and letters beyond Latin-1
+		gen = NewCollisionGenerator("\u00c5ngstr\u0151m", CollisionNormalization)
+		assert.Equal(t, "\u00c5ngstr\u0151m", gen.Generate(0))
+		assert.Equal(t, "A\u030angstro\u030bm", gen.Generate(1))
+
+		// decomposed names are composed first
+		gen = NewCollisionGenerator("cafe\u0301", CollisionNormalization)
+		assert.Equal(t, "caf\u00e9", gen.Generate(0))
+		assert.Equal(t, "cafe\u0301", gen.Generate(1))
+	})
+
+	t.Run("FileDir", func(t *testing.T) {
+		gen := NewCollisionGenerator("a", CollisionFileDir)
+		assert.Equal(t, "a", gen.Generate(0))
+		assert.Equal(t, "a/0", gen.Generate(1))
+	})
+}
+
+func TestGenerator(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Extension = "pdf"
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "06.pdf", gen.Generate(42))
+		assert.Equal(t, 6, Len(gen, 42))
+		assert.Equal(t, 7, Len(gen, 1332))
+	})
+
+	t.Run("Wordlist", func(t *testing.T) {
+		words, err := ReadWordlist(strings.NewReader("invoice\n\nreport\n"))
+		assert.NoError(t, err)
+
+		gen, err := NewGenerator(func(o *Options) {
+			o.Words = words
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "report", gen.Generate(1))
+		assert.Equal(t, "invoice-report", gen.Generate(3))
+
+		_, err = ReadWordlist(strings.NewReader("\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Template = "{{.Index}}-report.pdf"
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "42-report.pdf", gen.Generate(42))
+
+		_, err = NewGenerator(func(o *Options) {
+			o.Template = "{{.Unknown}}"
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Random = true
+			o.Seed = 42
+			o.Length = 12
+		})
+		assert.NoError(t, err)
+		assert.Len(t, gen.Generate(0), 12)
+		assert.Equal(t, gen.Generate(7), gen.Generate(7))
+		assert.NotEqual(t, gen.Generate(7), gen.Generate(8))
+		assert.Equal(t, 12, Len(gen, 0))
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Prefix = "docs/2024/"
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "docs/2024/0", gen.Generate(0))
+		assert.Equal(t, 11, Len(gen, 0))
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Collision = CollisionCase
+			o.CollisionName = "ab"
+			o.Prefix = "docs"
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "docs/ab", gen.Generate(0))
+		assert.Equal(t, "docs/Ab", gen.Generate(1))
+
+		_, err = NewGenerator(func(o *Options) {
+			o.Collision = CollisionDuplicate
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestShortestGenerator(t *testing.T) {
+	gen := NewShortestGenerator(300)
+
+	// the last names are the shortest
+	assert.Equal(t, "\x01", gen.Generate(299))
+	assert.Equal(t, 1, Len(gen, 48))
+	assert.Equal(t, 2, Len(gen, 47))
+	assert.Len(t, gen.Generate(0), 2)
+	assert.NotContains(t, string(ShortestAlphabet), "/")
+	assert.Len(t, ShortestAlphabet, 252)
+}
+
+func TestSpoof(t *testing.T) {
+	t.Run("Emoji", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Spoof = SpoofEmoji
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "\U0001F4A3", gen.Generate(0))
+		assert.Equal(t, "\U0001F4A3-\U0001F4C4", gen.Generate(len(Emojis)+1))
+	})
+
+	t.Run("RTL", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Template = "invoice{{.Index}}.pdf"
+			o.Spoof = SpoofRTL
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "invoice0\u202efdp.exe", gen.Generate(0))
+	})
+
+	t.Run("Homoglyph", func(t *testing.T) {
+		gen, err := NewGenerator(func(o *Options) {
+			o.Template = "paypal{{.Index}}.txt"
+			o.Spoof = SpoofHomoglyph
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "\u0440\u0430\u0443\u0440\u0430l0.txt", gen.Generate(0))
+	})
+
+	_, err := ParseSpoof("unknown")
+	assert.Error(t, err)
+}
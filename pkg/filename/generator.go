@@ -0,0 +1,310 @@
+package filename
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// Lengther is implemented by generators that know the length of a name
+// without generating it.
+type Lengther interface {
+	Len(i int) int
+}
+
+// Len returns the length of the name with index i. The length of the names
+// determines the header overhead and thereby the ratio of a zip bomb.
+func Len(gen Generator, i int) int {
+	if l, ok := gen.(Lengther); ok {
+		return l.Len(i)
+	}
+
+	return len(gen.Generate(i))
+}
+
+type Options struct {
+	// Alphabet of the default and the random generator.
+	Alphabet []byte
+
+	// Extension is appended to the names of all generators but the
+	// template generator.
+	Extension string
+
+	// Words are combined to names instead of the letters of the alphabet.
+	Words []string
+
+	// Template is a text/template pattern, e.g. "{{.Index}}-report.pdf".
+	Template string
+
+	// Random generates names of Length random letters, seeded by Seed.
+	Random bool
+	Seed   int64
+	Length int
+
+	// Shortest generates the shortest possible names for Count names.
+	Shortest bool
+	Count    int
+
+	// Collision generates names that collide with CollisionName.
+	Collision     Collision
+	CollisionName string
+
+	// Spoof generates emoji names, or RTL-override and homoglyph variants of
+	// the names of the other generators.
+	Spoof Spoof
+
+	// RealExtension is the real extension of RTL-override names.
+	RealExtension string
+
+	// Prefix is a directory path in front of all names.
+	Prefix string
+}
+
+// NewGenerator returns the generator selected by the options. Collision takes
+// precedence over Template, Template over Words, Words over Random, Random
+// over Shortest and Shortest over emoji names.
+func NewGenerator(optFns ...func(o *Options)) (Generator, error) {
+	opts := Options{
+		Alphabet:      DefaultAlphabet,
+		Length:        8,
+		RealExtension: "exe",
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	var (
+		gen Generator
+		err error
+	)
+
+	switch {
+	case opts.Collision != "":
+		if opts.CollisionName == "" {
+			return nil, fmt.Errorf("collision %q needs a name", opts.Collision)
+		}
+
+		gen = NewCollisionGenerator(opts.CollisionName, opts.Collision)
+	case opts.Template != "":
+		gen, err = NewTemplateGenerator(opts.Template)
+		if err != nil {
+			return nil, err
+		}
+	case len(opts.Words) > 0:
+		gen = NewWordlistGenerator(opts.Words, opts.Extension)
+	case opts.Random:
+		gen = NewRandomGenerator(opts.Seed, opts.Length, opts.Alphabet, opts.Extension)
+	case opts.Shortest:
+		gen = NewShortestGenerator(opts.Count)
+	case opts.Spoof == SpoofEmoji:
+		gen = NewEmojiGenerator(opts.Extension)
+	default:
+		gen = NewDefaultGenerator(opts.Alphabet, opts.Extension)
+	}
+
+	switch opts.Spoof {
+	case SpoofRTL:
+		gen = NewRTLGenerator(gen, opts.RealExtension)
+	case SpoofHomoglyph:
+		gen = NewHomoglyphGenerator(gen)
+	case SpoofEmoji:
+	}
+
+	if opts.Prefix != "" {
+		gen = NewPrefixGenerator(opts.Prefix, gen)
+	}
+
+	return gen, nil
+}
+
+type WordlistGenerator struct {
+	words     []string
+	extension string
+}
+
+// NewWordlistGenerator returns a generator that uses the words like the
+// default generator uses the letters of its alphabet, joined by "-".
+func NewWordlistGenerator(words []string, extension string) Generator {
+	return &WordlistGenerator{
+		words:     words,
+		extension: extension,
+	}
+}
+
+func (g *WordlistGenerator) Generate(i int) string {
+	words := []string{}
+
+	for {
+		words = append([]string{g.words[i%len(g.words)]}, words...)
+
+		i = i/len(g.words) - 1
+		if i < 0 {
+			break
+		}
+	}
+
+	name := strings.Join(words, "-")
+
+	if g.extension != "" {
+		return fmt.Sprintf("%s.%s", name, g.extension)
+	}
+
+	return name
+}
+
+// ReadWordlist reads one word per line and skips empty lines.
+func ReadWordlist(r io.Reader) ([]string, error) {
+	var words []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			words = append(words, word)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty wordlist")
+	}
+
+	return words, nil
+}
+
+// TemplateData is passed to the template of a TemplateGenerator.
+type TemplateData struct {
+	// Index is the index of the name.
+	Index int
+
+	// Name is the name of the default generator.
+	Name string
+}
+
+type TemplateGenerator struct {
+	tmpl *template.Template
+	gen  Generator
+}
+
+// NewTemplateGenerator returns a generator that executes a text/template
+// with TemplateData, e.g. "{{.Index}}-report.pdf".
+func NewTemplateGenerator(pattern string) (Generator, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &TemplateGenerator{
+		tmpl: tmpl,
+		gen:  NewDefaultGenerator(DefaultAlphabet, ""),
+	}
+
+	// fail early on templates that cannot be executed
+	if err := tmpl.Execute(io.Discard, g.data(0)); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *TemplateGenerator) Generate(i int) string {
+	var buf bytes.Buffer
+
+	// the template has been executed successfully in the constructor
+	_ = g.tmpl.Execute(&buf, g.data(i))
+
+	return buf.String()
+}
+
+func (g *TemplateGenerator) data(i int) TemplateData {
+	return TemplateData{
+		Index: i,
+		Name:  g.gen.Generate(i),
+	}
+}
+
+type RandomGenerator struct {
+	seed      int64
+	length    int
+	alphabet  []byte
+	extension string
+}
+
+// NewRandomGenerator returns a generator of random names of the given length.
+// The name of an index only depends on the seed, so names can be generated in
+// any order. Short names may collide.
+func NewRandomGenerator(seed int64, length int, alphabet []byte, extension string) Generator {
+	g := &RandomGenerator{
+		seed:      seed,
+		length:    length,
+		alphabet:  alphabet,
+		extension: extension,
+	}
+
+	if len(g.alphabet) == 0 {
+		g.alphabet = DefaultAlphabet
+	}
+
+	return g
+}
+
+func (g *RandomGenerator) Generate(i int) string {
+	x := uint64(g.seed) ^ uint64(i)*0x9e3779b97f4a7c15
+	letters := make([]byte, g.length)
+
+	for j := range letters {
+		x = splitmix64(x)
+		letters[j] = g.alphabet[x%uint64(len(g.alphabet))]
+	}
+
+	if g.extension != "" {
+		return fmt.Sprintf("%s.%s", letters, g.extension)
+	}
+
+	return string(letters)
+}
+
+func (g *RandomGenerator) Len(i int) int {
+	if g.extension != "" {
+		return g.length + 1 + len(g.extension)
+	}
+
+	return g.length
+}
+
+// splitmix64 returns the next value of a SplitMix64 sequence.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+
+	return x ^ (x >> 31)
+}
+
+type PrefixGenerator struct {
+	prefix string
+	gen    Generator
+}
+
+// NewPrefixGenerator returns a generator that puts the names of gen into the
+// directory prefix.
+func NewPrefixGenerator(prefix string, gen Generator) Generator {
+	return &PrefixGenerator{
+		prefix: strings.TrimSuffix(prefix, "/"),
+		gen:    gen,
+	}
+}
+
+func (g *PrefixGenerator) Generate(i int) string {
+	return g.prefix + "/" + g.gen.Generate(i)
+}
+
+func (g *PrefixGenerator) Len(i int) int {
+	return len(g.prefix) + 1 + Len(g.gen, i)
+}
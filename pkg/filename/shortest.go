@@ -0,0 +1,51 @@
+package filename
+
+// ShortestAlphabet holds the bytes 0x01-0xFF without the path separators and
+// the dot, so that no name is "." or "..".
+var ShortestAlphabet = func() []byte {
+	alphabet := make([]byte, 0, 252)
+
+	for b := 0x01; b <= 0xff; b++ {
+		if b == '/' || b == '\\' || b == '.' {
+			continue
+		}
+
+		alphabet = append(alphabet, byte(b))
+	}
+
+	return alphabet
+}()
+
+type ShortestGenerator struct {
+	gen   *DefaultGenerator
+	count int
+}
+
+// NewShortestGenerator returns a generator of the shortest possible names
+// for count names: 252 names of one byte, then names of two bytes. The order
+// is reversed, so the last files, whose headers are quoted by the most
+// overlapping files, get the shortest names.
+func NewShortestGenerator(count int) Generator {
+	return &ShortestGenerator{
+		gen: &DefaultGenerator{
+			alphabet: ShortestAlphabet,
+		},
+		count: count,
+	}
+}
+
+func (g *ShortestGenerator) Generate(i int) string {
+	return g.gen.Generate(g.rank(i))
+}
+
+func (g *ShortestGenerator) Len(i int) int {
+	return g.gen.Len(g.rank(i))
+}
+
+func (g *ShortestGenerator) rank(i int) int {
+	if i < g.count {
+		return g.count - 1 - i
+	}
+
+	return i
+}
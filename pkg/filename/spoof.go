@@ -0,0 +1,130 @@
+package filename
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Spoof is a kind of names that test the display of filenames.
+type Spoof string
+
+const (
+	// SpoofEmoji generates names of emoji.
+	SpoofEmoji Spoof = "emoji"
+
+	// SpoofRTL hides the real extension of names behind a right-to-left
+	// override, e.g. "0<RLO>fdp.exe" is displayed as "0exe.pdf".
+	SpoofRTL Spoof = "rtl"
+
+	// SpoofHomoglyph replaces latin letters by Cyrillic and Greek look-alikes.
+	SpoofHomoglyph Spoof = "homoglyph"
+)
+
+// ParseSpoof returns the spoof with the given name.
+func ParseSpoof(name string) (Spoof, error) {
+	switch s := Spoof(name); s {
+	case "", SpoofEmoji, SpoofRTL, SpoofHomoglyph:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown spoof %q", name)
+	}
+}
+
+// Emojis is the alphabet of the emoji generator.
+var Emojis = []string{
+	"\U0001F4A3",                 // bomb
+	"\U0001F4C4",                 // page facing up
+	"\U0001F525",                 // fire
+	"\U0001F600",                 // grinning face
+	"\U0001F680",                 // rocket
+	"\U0001F4BE",                 // floppy disk
+	"\U0001F4E6",                 // package
+	"\U0001F389",                 // party popper
+	"\U0001F468\u200d\U0001F4BB", // technologist, a zero width joiner sequence
+	"\U0001F1E9\U0001F1EA",       // flag, a regional indicator pair
+}
+
+// NewEmojiGenerator returns a generator of names made of emoji.
+func NewEmojiGenerator(extension string) Generator {
+	return &WordlistGenerator{
+		words:     Emojis,
+		extension: extension,
+	}
+}
+
+const rightToLeftOverride = "\u202e"
+
+type RTLGenerator struct {
+	gen           Generator
+	realExtension string
+}
+
+// NewRTLGenerator returns a generator that keeps the displayed extension of
+// the names of gen, "pdf" for names without one, but whose real extension is
+// realExtension.
+func NewRTLGenerator(gen Generator, realExtension string) Generator {
+	return &RTLGenerator{
+		gen:           gen,
+		realExtension: realExtension,
+	}
+}
+
+func (g *RTLGenerator) Generate(i int) string {
+	name := g.gen.Generate(i)
+
+	shown := strings.TrimPrefix(path.Ext(name), ".")
+	stem := strings.TrimSuffix(name, path.Ext(name))
+
+	if shown == "" {
+		shown = "pdf"
+	}
+
+	// the override displays the remainder of the name reversed
+	return stem + rightToLeftOverride + reverse(shown) + "." + g.realExtension
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
+
+// homoglyphs maps latin letters to Cyrillic and Greek look-alikes.
+var homoglyphs = map[rune]rune{
+	'A': '\u0410', 'B': '\u0412', 'C': '\u0421', 'E': '\u0415', 'H': '\u041d',
+	'I': '\u0406', 'K': '\u041a', 'M': '\u041c', 'N': '\u039d', 'O': '\u041e',
+	'P': '\u0420', 'T': '\u0422', 'X': '\u0425', 'Y': '\u03a5', 'Z': '\u0396',
+	'a': '\u0430', 'c': '\u0441', 'e': '\u0435', 'i': '\u0456', 'j': '\u0458',
+	'o': '\u043e', 'p': '\u0440', 's': '\u0455', 'x': '\u0445', 'y': '\u0443',
+}
+
+type HomoglyphGenerator struct {
+	gen Generator
+}
+
+// NewHomoglyphGenerator returns a generator that replaces the latin letters
+// of the names of gen, but not of their extensions, by look-alikes.
+func NewHomoglyphGenerator(gen Generator) Generator {
+	return &HomoglyphGenerator{
+		gen: gen,
+	}
+}
+
+func (g *HomoglyphGenerator) Generate(i int) string {
+	name := g.gen.Generate(i)
+	ext := path.Ext(name)
+
+	// the extension is kept, so the file type does not change
+	return strings.Map(func(r rune) rune {
+		if h, ok := homoglyphs[r]; ok {
+			return h
+		}
+
+		return r
+	}, strings.TrimSuffix(name, ext)) + ext
+}
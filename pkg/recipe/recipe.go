@@ -0,0 +1,453 @@
+package recipe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hupe1980/zipbomb/pkg/filename"
+	"github.com/hupe1980/zipbomb/pkg/zipbomb"
+	"gopkg.in/yaml.v3"
+)
+
+// Segment types.
+const (
+	TypeOverlap   = "overlap"
+	TypeNoOverlap = "no-overlap"
+	TypeZipSlip   = "zip-slip"
+	TypeFile      = "file"
+)
+
+// Recipe describes an archive composed of ordered segments.
+type Recipe struct {
+	// Comment is the comment of the end of central directory record.
+	Comment  string    `yaml:"comment"`
+	Segments []Segment `yaml:"segments"`
+
+	// dir is the base directory for relative paths in the recipe.
+	dir string
+}
+
+// Segment is a part of the archive.
+type Segment struct {
+	Type string `yaml:"type"`
+
+	// Files is the number of files of an overlap or no-overlap segment.
+	Files int `yaml:"files"`
+
+	// Names are the entry names of a zip-slip segment.
+	Names []string `yaml:"names"`
+
+	// Name is the entry name of a file segment.
+	Name string `yaml:"name"`
+
+	// Path is the file on disk that provides the content of a file or
+	// zip-slip segment instead of the kernel.
+	Path string `yaml:"path"`
+
+	Method           string `yaml:"method"`
+	CompressionLevel *int   `yaml:"level"`
+	ExtraTag         uint16 `yaml:"extra_tag"`
+	Comment          string `yaml:"comment"`
+
+	// Password encrypts the entries of a no-overlap, zip-slip or file
+	// segment. Encryption is zipcrypto (default), aes128, aes192 or aes256.
+	Password   string `yaml:"password"`
+	Encryption string `yaml:"encryption"`
+	AE2        bool   `yaml:"ae2"`
+
+	Filenames Filenames `yaml:"filenames"`
+	Kernel    Kernel    `yaml:"kernel"`
+}
+
+// Filenames configures the filename generator of a segment.
+type Filenames struct {
+	Alphabet  string `yaml:"alphabet"`
+	Extension string `yaml:"extension"`
+
+	// Wordlist is a file with one word per line.
+	Wordlist string `yaml:"wordlist"`
+
+	// Template is a text/template pattern, e.g. "{{.Index}}-report.pdf".
+	Template string `yaml:"template"`
+
+	// Seed selects random filenames of Length letters.
+	Seed   *int64 `yaml:"seed"`
+	Length int    `yaml:"length"`
+
+	// Spoof is emoji, rtl or homoglyph.
+	Spoof         string `yaml:"spoof"`
+	RealExtension string `yaml:"real_extension"`
+
+	Prefix string `yaml:"prefix"`
+
+	// ShortestNames selects the shortest possible names of any byte but the
+	// path separators.
+	ShortestNames bool `yaml:"shortest_names"`
+
+	// Collision is duplicate, case, normalization or file-dir. The names
+	// collide with CollisionName.
+	Collision     string `yaml:"collision"`
+	CollisionName string `yaml:"collision_name"`
+}
+
+// Kernel configures the kernel of a segment.
+type Kernel struct {
+	Bytes   string `yaml:"bytes"` // hex encoded
+	Repeats int    `yaml:"repeats"`
+
+	// File is a file on disk with the content of the kernel.
+	File string `yaml:"file"`
+
+	// Content is json, xml, csv or log. Repeats is the number of records.
+	Content string `yaml:"content"`
+}
+
+// Load parses a recipe. Relative paths are resolved against dir.
+func Load(r io.Reader, dir string) (*Recipe, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	rcp := &Recipe{}
+	if err := dec.Decode(rcp); err != nil {
+		return nil, err
+	}
+
+	rcp.dir = dir
+
+	for i := range rcp.Segments {
+		if err := rcp.Segments[i].validate(); err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+	}
+
+	return rcp, nil
+}
+
+// LoadFile parses the recipe file with the given name.
+func LoadFile(name string) (*Recipe, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return Load(f, filepath.Dir(name))
+}
+
+// NumFiles returns the number of entries the recipe produces.
+func (r *Recipe) NumFiles() int {
+	n := 0
+
+	for i := range r.Segments {
+		switch s := &r.Segments[i]; s.Type {
+		case TypeOverlap, TypeNoOverlap:
+			n += s.Files
+		case TypeZipSlip:
+			n += len(s.Names)
+		case TypeFile:
+			n++
+		}
+	}
+
+	return n
+}
+
+type MakeOptions struct {
+	OnFileCreateHook zipbomb.OnFileCreateHookFunc
+}
+
+// Make writes the archive described by the recipe to w.
+func (r *Recipe) Make(w io.Writer, optFns ...func(o *MakeOptions)) (*zipbomb.ZipBomb, error) {
+	opts := MakeOptions{}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	onFileCreate := func(name string) {
+		if opts.OnFileCreateHook != nil {
+			opts.OnFileCreateHook(name)
+		}
+	}
+
+	zbomb, err := zipbomb.New(w, func(o *zipbomb.Options) {
+		o.EOCDComment = r.Comment
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range r.Segments {
+		if err := r.addSegment(zbomb, &r.Segments[i], onFileCreate); err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+	}
+
+	if err := zbomb.Close(); err != nil {
+		return nil, err
+	}
+
+	return zbomb, nil
+}
+
+func (r *Recipe) addSegment(zbomb *zipbomb.ZipBomb, s *Segment, onFileCreate zipbomb.OnFileCreateHookFunc) error {
+	method, err := zipbomb.ParseMethod(s.Method)
+	if err != nil {
+		return err
+	}
+
+	encryptionMethod, err := zipbomb.ParseEncryption(s.Encryption)
+	if err != nil {
+		return err
+	}
+
+	encryption := zipbomb.EncryptionOptions{
+		Password:   s.Password,
+		Encryption: encryptionMethod,
+		AESVersion: zipbomb.AE1,
+	}
+
+	if s.AE2 {
+		encryption.AESVersion = zipbomb.AE2
+	}
+
+	level := 5
+	if s.CompressionLevel != nil {
+		level = *s.CompressionLevel
+	}
+
+	switch s.Type {
+	case TypeOverlap, TypeNoOverlap:
+		kb, err := r.kernel(&s.Kernel)
+		if err != nil {
+			return err
+		}
+
+		filenameGen, err := r.filenameGenerator(&s.Filenames, s.Files)
+		if err != nil {
+			return err
+		}
+
+		optFn := func(o *zipbomb.OverlapOptions) {
+			o.FilenameGen = filenameGen
+			o.CompressionLevel = level
+			o.Method = method
+			o.ExtraTag = s.ExtraTag
+			o.Comment = s.Comment
+			o.EncryptionOptions = encryption
+			o.OnFileCreateHook = onFileCreate
+		}
+
+		if s.Type == TypeOverlap {
+			return zbomb.AddEscapedOverlap(kb, s.Files, optFn)
+		}
+
+		return zbomb.AddNoOverlap(kb, s.Files, optFn)
+	case TypeFile:
+		f, err := os.Open(r.path(s.Path))
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		finfo, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		if err := zbomb.AddFile(s.Name, f, func(o *zipbomb.FileOptions) {
+			o.CompressionLevel = level
+			o.Method = method
+			o.FileMode = finfo.Mode()
+			o.Modified = finfo.ModTime()
+			o.Comment = s.Comment
+			o.EncryptionOptions = encryption
+		}); err != nil {
+			return err
+		}
+
+		onFileCreate(s.Name)
+	case TypeZipSlip:
+		content, mode, err := r.content(s)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range s.Names {
+			if err := zbomb.AddZipSlip(content, name, func(o *zipbomb.ZipSlipOptions) {
+				o.CompressionLevel = level
+				o.Method = method
+				o.FileMode = mode
+				o.Comment = s.Comment
+				o.EncryptionOptions = encryption
+			}); err != nil {
+				return err
+			}
+
+			onFileCreate(name)
+		}
+	}
+
+	return nil
+}
+
+// content returns the file content of a segment and its file mode.
+func (r *Recipe) content(s *Segment) (zipbomb.Kernel, os.FileMode, error) {
+	if s.Path == "" {
+		kb, err := r.kernel(&s.Kernel)
+		return kb, 0, err
+	}
+
+	path := r.path(s.Path)
+
+	finfo, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	kb, err := zipbomb.NewFileKernel(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return kb, finfo.Mode(), nil
+}
+
+// filenameGenerator returns the filename generator of a segment with count
+// files.
+func (r *Recipe) filenameGenerator(f *Filenames, count int) (filename.Generator, error) {
+	spoof, err := filename.ParseSpoof(f.Spoof)
+	if err != nil {
+		return nil, err
+	}
+
+	var collision filename.Collision
+
+	if f.Collision != "" {
+		collision, err = filename.ParseCollision(f.Collision)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var words []string
+
+	if f.Wordlist != "" {
+		file, err := os.Open(r.path(f.Wordlist))
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		words, err = filename.ReadWordlist(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return filename.NewGenerator(func(o *filename.Options) {
+		if f.Alphabet != "" {
+			o.Alphabet = []byte(f.Alphabet)
+		}
+
+		o.Extension = f.Extension
+		o.Words = words
+		o.Template = f.Template
+		o.Prefix = f.Prefix
+		o.Spoof = spoof
+		o.Shortest = f.ShortestNames
+		o.Count = count
+		o.Collision = collision
+		o.CollisionName = f.CollisionName
+
+		if f.RealExtension != "" {
+			o.RealExtension = f.RealExtension
+		}
+
+		if f.Seed != nil {
+			o.Random = true
+			o.Seed = *f.Seed
+		}
+
+		if f.Length > 0 {
+			o.Length = f.Length
+		}
+	})
+}
+
+// path resolves a path relative to the recipe.
+func (r *Recipe) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+
+	return filepath.Join(r.dir, p)
+}
+
+func (s *Segment) validate() error {
+	if s.Method == "" {
+		s.Method = "deflate"
+	}
+
+	switch s.Type {
+	case TypeOverlap, TypeNoOverlap:
+		if s.Files < 1 {
+			return fmt.Errorf("%s segment needs at least one file", s.Type)
+		}
+	case TypeZipSlip:
+		if len(s.Names) == 0 {
+			return fmt.Errorf("%s segment needs at least one name", s.Type)
+		}
+	case TypeFile:
+		if s.Name == "" || s.Path == "" {
+			return fmt.Errorf("%s segment needs a name and a path", s.Type)
+		}
+	default:
+		return fmt.Errorf("unknown segment type %q", s.Type)
+	}
+
+	if _, err := zipbomb.ParseEncryption(s.Encryption); err != nil {
+		return err
+	}
+
+	_, err := zipbomb.ParseMethod(s.Method)
+
+	return err
+}
+
+// kernel returns the kernel of a segment.
+func (r *Recipe) kernel(k *Kernel) (zipbomb.Kernel, error) {
+	repeats := int64(k.Repeats)
+	if repeats == 0 {
+		repeats = 1024 * 1024
+	}
+
+	switch {
+	case k.File != "":
+		return zipbomb.NewFileKernel(r.path(k.File))
+	case k.Content != "":
+		format, err := zipbomb.ParseContentFormat(k.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		return zipbomb.NewContentKernel(format, repeats)
+	}
+
+	kb := []byte{'B'}
+
+	if k.Bytes != "" {
+		var err error
+		if kb, err = hex.DecodeString(k.Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return zipbomb.RepeatKernel{Bytes: kb, Repeats: repeats}, nil
+}
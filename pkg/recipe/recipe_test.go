@@ -0,0 +1,125 @@
+package recipe
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipe(t *testing.T) {
+	rcp, err := Load(strings.NewReader(`
+comment: eocd
+segments:
+  - type: overlap
+    files: 3
+    kernel:
+      repeats: 10
+  - type: no-overlap
+    files: 2
+    comment: entry
+    filenames:
+      alphabet: XY
+  - type: zip-slip
+    names: ["../slip"]
+`), ".")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, rcp.NumFiles())
+
+	buffer := new(bytes.Buffer)
+
+	_, err = rcp.Make(buffer)
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, "eocd", r.Comment)
+	assert.Len(t, r.File, 6)
+	assert.Equal(t, "X", r.File[3].Name)
+	assert.Equal(t, "entry", r.File[3].Comment)
+	assert.Equal(t, "../slip", r.File[5].Name)
+}
+
+func TestRecipeFilenames(t *testing.T) {
+	rcp, err := Load(strings.NewReader(`
+segments:
+  - type: no-overlap
+    files: 2
+    filenames:
+      template: "{{.Index}}-report.pdf"
+      prefix: docs
+`), ".")
+	assert.NoError(t, err)
+
+	buffer := new(bytes.Buffer)
+
+	_, err = rcp.Make(buffer)
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, "docs/0-report.pdf", r.File[0].Name)
+	assert.Equal(t, "docs/1-report.pdf", r.File[1].Name)
+
+	t.Run("Shortest names", func(t *testing.T) {
+		rcp, err := Load(strings.NewReader(`
+segments:
+  - type: no-overlap
+    files: 300
+    filenames:
+      shortest_names: true
+`), ".")
+		assert.NoError(t, err)
+
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := rcp.Make(buffer)
+		assert.NoError(t, err)
+		assert.Greater(t, zbomb.Report().RatioGain, 1.0)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, r.File[0].Name, 2)
+		assert.Len(t, r.File[299].Name, 1)
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		rcp, err := Load(strings.NewReader(`
+segments:
+  - type: overlap
+    files: 2
+    filenames:
+      collision: duplicate
+      collision_name: readme.txt
+`), ".")
+		assert.NoError(t, err)
+
+		buffer := new(bytes.Buffer)
+
+		_, err = rcp.Make(buffer)
+		assert.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Equal(t, "readme.txt", r.File[0].Name)
+		assert.Equal(t, "readme.txt", r.File[1].Name)
+	})
+}
+
+func TestRecipeValidation(t *testing.T) {
+	_, err := Load(strings.NewReader(`
+segments:
+  - type: unknown
+`), ".")
+	assert.Error(t, err)
+
+	_, err = Load(strings.NewReader(`
+segments:
+  - type: overlap
+    files: 1
+    method: lzma
+`), ".")
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"os"
 )
 
 type Options struct {
@@ -337,7 +338,7 @@ func makeGeneric(zhead, head, ztail, tail, crc []byte) ([]byte, error) {
 		for {
 			if crc0&0xfffff == 0 {
 				//PROGRESS
-				fmt.Printf("%#f%%\r", 100*float64(crc0)/0xffffffff)
+				fmt.Fprintf(os.Stderr, "%#f%%\r", 100*float64(crc0)/0xffffffff)
 			}
 
 			for _, i := range embed {
@@ -355,7 +356,7 @@ func makeGeneric(zhead, head, ztail, tail, crc []byte) ([]byte, error) {
 			crc0++
 		}
 
-		fmt.Printf("SUCCESS   \n")
+		fmt.Fprintf(os.Stderr, "SUCCESS   \n")
 	}
 
 	// double double-check
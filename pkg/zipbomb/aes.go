@@ -0,0 +1,232 @@
+package zipbomb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"io"
+)
+
+// WinZip AES encryption. See https://www.winzip.com/en/support/aes-encryption/
+const (
+	aesMethod          = 99
+	aesVendorID        = 0x4541 // "AE"
+	aesIterations      = 1000
+	aesVerifierLen     = 2
+	aesAuthCodeLen     = 10
+	aesExtraDataLen    = 7
+	aesCounterBlockLen = aes.BlockSize
+)
+
+// aesKeyLen returns the key length of an AES encryption.
+func aesKeyLen(method Encryption) int {
+	switch method {
+	case AES128:
+		return 16
+	case AES192:
+		return 24
+	default:
+		return 32
+	}
+}
+
+// aesStrength returns the strength of an AES encryption in the AES extra
+// field.
+func aesStrength(method Encryption) uint8 {
+	switch method {
+	case AES128:
+		return 1
+	case AES192:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// aesExtra returns the AES extra field.
+func aesExtra(method Encryption, version AESVersion, compression uint16) []byte {
+	buf := make([]byte, 4+aesExtraDataLen)
+	b := writeBuf(buf)
+	b.uint16(aesExtraID)
+	b.uint16(aesExtraDataLen)
+	b.uint16(uint16(version) + 1) // vendor version AE-1 or AE-2
+	b.uint16(aesVendorID)
+	b.uint8(aesStrength(method))
+	b.uint16(compression)
+
+	return buf
+}
+
+// withAES returns copies of the local and the central header of an entry
+// that is encrypted with WinZip AES, and its encrypted data.
+func withAES(header, central *fileHeader, data io.WriterTo, password string, method Encryption, version AESVersion) (*fileHeader, *fileHeader, io.WriterTo) {
+	saltLen := aesKeyLen(method) / 2
+
+	encrypted := func(h *fileHeader) *fileHeader {
+		c := *h
+		c.Flags |= flagEncrypted
+		c.Method = aesMethod
+		c.CompressedSize64 += uint64(saltLen + aesVerifierLen + aesAuthCodeLen)
+		c.Extra = append(append([]byte{}, h.Extra...), aesExtra(method, version, h.Method)...)
+
+		if version == AE2 {
+			c.CRC32 = 0
+		}
+
+		if c.ReaderVersion < zipVersion51 {
+			c.ReaderVersion = zipVersion51
+			c.CreatorVersion = c.CreatorVersion&0xff00 | zipVersion51
+		}
+
+		c.updateZip64()
+
+		return &c
+	}
+
+	lfh := encrypted(header)
+
+	cdh := lfh
+	if central != header {
+		cdh = encrypted(central)
+	}
+
+	return lfh, cdh, &aesData{data: data, password: password, keyLen: aesKeyLen(method)}
+}
+
+// aesData is file data that is encrypted while it is written. Every write
+// uses a new random salt.
+type aesData struct {
+	data     io.WriterTo
+	password string
+	keyLen   int
+}
+
+func (d *aesData) WriteTo(w io.Writer) (int64, error) {
+	salt := make([]byte, d.keyLen/2)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, err
+	}
+
+	keys := pbkdf2SHA1([]byte(d.password), salt, aesIterations, 2*d.keyLen+aesVerifierLen)
+	encKey, authKey, verifier := keys[:d.keyLen], keys[d.keyLen:2*d.keyLen], keys[2*d.keyLen:]
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+
+	n, err := w.Write(append(salt, verifier...))
+	written += int64(n)
+
+	if err != nil {
+		return written, err
+	}
+
+	aw := &aesWriter{w: w, ctr: newAESCTR(block), mac: hmac.New(sha1.New, authKey)}
+
+	if d.data != nil {
+		m, err := d.data.WriteTo(aw)
+		written += m
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	n, err = w.Write(aw.mac.Sum(nil)[:aesAuthCodeLen])
+	written += int64(n)
+
+	return written, err
+}
+
+// aesWriter encrypts and authenticates the data written to w.
+type aesWriter struct {
+	w   io.Writer
+	ctr *aesCTR
+	mac hash.Hash
+	buf []byte
+}
+
+func (w *aesWriter) Write(p []byte) (int, error) {
+	if cap(w.buf) < len(p) {
+		w.buf = make([]byte, len(p))
+	}
+
+	buf := w.buf[:len(p)]
+	w.ctr.XORKeyStream(buf, p)
+	w.mac.Write(buf)
+
+	return w.w.Write(buf)
+}
+
+// aesCTR is the counter mode of WinZip AES, which increments the counter as
+// a little-endian integer starting at 1. cipher.NewCTR counts big-endian.
+type aesCTR struct {
+	block   cipher.Block
+	counter [aesCounterBlockLen]byte
+	stream  [aesCounterBlockLen]byte
+	used    int
+}
+
+func newAESCTR(block cipher.Block) *aesCTR {
+	return &aesCTR{block: block, used: aesCounterBlockLen}
+}
+
+func (c *aesCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.used == aesCounterBlockLen {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.used = 0
+		}
+
+		dst[i] = src[i] ^ c.stream[c.used]
+		c.used++
+	}
+}
+
+// pbkdf2SHA1 derives a key with PBKDF2 and HMAC-SHA1 as in RFC 8018.
+func pbkdf2SHA1(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha1.New, password)
+	numBlocks := (keyLen + sha1.Size - 1) / sha1.Size
+
+	var index [4]byte
+
+	dk := make([]byte, 0, numBlocks*sha1.Size)
+	u := make([]byte, sha1.Size)
+
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(index[:], uint32(block))
+		prf.Write(index[:])
+
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-sha1.Size:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+
+	return dk[:keyLen]
+}
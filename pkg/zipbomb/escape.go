@@ -1,14 +1,12 @@
 package zipbomb
 
-import "hash"
-
 type escape struct {
 	lfh  *fileHeader
 	data []byte
 	name string
 }
 
-func newEscape(name string, header *fileHeader, numEscaped uint16, crc32 hash.Hash32) *escape {
+func newEscape(name string, header *fileHeader, numEscaped uint16, crc32 uint32) *escape {
 	var buf [5]byte
 	b := writeBuf(buf[:])
 	b.uint8(0x00)                 // BTYPE=00 => no compression
@@ -16,9 +14,9 @@ func newEscape(name string, header *fileHeader, numEscaped uint16, crc32 hash.Ha
 	b.uint16(numEscaped ^ 0xffff) // NLEN => one's complement of LEN
 
 	lfh := newFileHeader(
-		uint64(uint32(len(buf))+uint32(numEscaped)+header.CompressedSize),
-		uint64(uint32(numEscaped)+header.UncompressedSize),
-		crc32.Sum32(),
+		uint64(len(buf))+uint64(numEscaped)+header.CompressedSize64,
+		uint64(numEscaped)+header.UncompressedSize64,
+		crc32,
 		name,
 		Deflate,
 	)
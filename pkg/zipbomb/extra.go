@@ -0,0 +1,211 @@
+package zipbomb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"time"
+)
+
+// ExtendedTimestampExtra returns an extended timestamp extra field with the
+// modification, access and creation times that are not zero. The times must
+// lie between 1970 and 2106. The central directory keeps only the
+// modification time of the field.
+func ExtendedTimestampExtra(modified, accessed, created time.Time) ([]byte, error) {
+	var (
+		flags byte
+		times []time.Time
+	)
+
+	for i, t := range []time.Time{modified, accessed, created} {
+		if t.IsZero() {
+			continue
+		}
+
+		if t.Unix() < 0 || t.Unix() > math.MaxUint32 {
+			return nil, fmt.Errorf("%w: %s", errTimestampRange, t)
+		}
+
+		flags |= 1 << i
+		times = append(times, t)
+	}
+
+	buf := make([]byte, 5+4*len(times))
+	b := writeBuf(buf)
+	b.uint16(extTimeExtraID)
+	b.uint16(uint16(1 + 4*len(times)))
+	b.uint8(flags)
+
+	for _, t := range times {
+		b.uint32(uint32(t.Unix()))
+	}
+
+	return buf, nil
+}
+
+// centralTimestampExtra returns the extra fields of the central directory
+// for the extra fields of a local header. Unlike the local form, the central
+// extended timestamp holds the modification time only, but its flags still
+// tell the times of the local form.
+func centralTimestampExtra(extra []byte) []byte {
+	out := []byte{}
+
+	for len(extra) >= 4 {
+		size := 4 + int(binary.LittleEndian.Uint16(extra[2:4]))
+		if size > len(extra) {
+			break
+		}
+
+		field := extra[:size]
+
+		if binary.LittleEndian.Uint16(field[:2]) == extTimeExtraID && size > 5 {
+			n := 1
+			if field[4]&1 != 0 && size >= 9 {
+				n = 5
+			}
+
+			buf := make([]byte, 4+n)
+			b := writeBuf(buf)
+			b.uint16(extTimeExtraID)
+			b.uint16(uint16(n))
+			copy(b, field[4:4+n])
+
+			field = buf
+		}
+
+		out = append(out, field...)
+		extra = extra[size:]
+	}
+
+	return out
+}
+
+// UnixOwnerExtra returns an Info-ZIP Unix extra field with the user and group
+// id of the owner.
+func UnixOwnerExtra(uid, gid uint32) []byte {
+	buf := make([]byte, 15)
+	b := writeBuf(buf)
+	b.uint16(infoZipUnixExtraID)
+	b.uint16(11)
+	b.uint8(1) // version
+	b.uint8(4) // size of uid
+	b.uint32(uid)
+	b.uint8(4) // size of gid
+	b.uint32(gid)
+
+	return buf
+}
+
+// NTFSExtra returns an NTFS extra field with the modification, access and
+// creation times. The times must not lie before 1601.
+func NTFSExtra(modified, accessed, created time.Time) ([]byte, error) {
+	buf := make([]byte, 36)
+	b := writeBuf(buf)
+	b.uint16(ntfsExtraID)
+	b.uint16(32)
+	b.uint32(0) // reserved
+	b.uint16(1) // attribute tag 1
+	b.uint16(24)
+
+	for _, t := range []time.Time{modified, accessed, created} {
+		ft, err := timeToFiletime(t)
+		if err != nil {
+			return nil, err
+		}
+
+		b.uint64(ft)
+	}
+
+	return buf, nil
+}
+
+// UnknownExtra returns an extra field with an arbitrary tag and payload. The
+// field, header included, must fit into the extra fields of an entry.
+func UnknownExtra(tag uint16, payload []byte) ([]byte, error) {
+	if 4+len(payload) > uint16max {
+		return nil, errLongExtra
+	}
+
+	buf := make([]byte, 4+len(payload))
+	b := writeBuf(buf)
+	b.uint16(tag)
+	b.uint16(uint16(len(payload)))
+	copy(b, payload)
+
+	return buf, nil
+}
+
+// timeToFiletime returns t in 100ns intervals since January 1, 1601 UTC.
+func timeToFiletime(t time.Time) (uint64, error) {
+	if t.IsZero() {
+		return 0, nil
+	}
+
+	const (
+		epochDiff = 11644473600 // 1601 to 1970 in seconds
+		maxSecs   = (math.MaxUint64 - 9999999) / 10000000
+	)
+
+	secs := t.Unix() + epochDiff
+	if t.Unix() > maxSecs-epochDiff || secs < 0 {
+		return 0, fmt.Errorf("%w: %s", errFiletimeRange, t)
+	}
+
+	return uint64(secs)*10000000 + uint64(t.Nanosecond()/100), nil
+}
+
+// unicodePathExtra returns an Info-ZIP Unicode Path extra field that
+// replaces name with unicodeName in readers that support it.
+func unicodePathExtra(name, unicodeName string) []byte {
+	buf := make([]byte, 9+len(unicodeName))
+	b := writeBuf(buf)
+	b.uint16(unicodePathExtraID)
+	b.uint16(uint16(5 + len(unicodeName)))
+	b.uint8(1) // version
+	b.uint32(crc32.ChecksumIEEE([]byte(name)))
+	copy(b, unicodeName)
+
+	return buf
+}
+
+// unicodeCommentExtra returns an Info-ZIP Unicode Comment extra field that
+// replaces comment with unicodeComment in readers that support it.
+func unicodeCommentExtra(comment, unicodeComment string) []byte {
+	buf := make([]byte, 9+len(unicodeComment))
+	b := writeBuf(buf)
+	b.uint16(unicodeCommentExtraID)
+	b.uint16(uint16(5 + len(unicodeComment)))
+	b.uint8(1) // version
+	b.uint32(crc32.ChecksumIEEE([]byte(comment)))
+	copy(b, unicodeComment)
+
+	return buf
+}
+
+// hasExtra reports whether the extra fields contain a block with the given
+// tag.
+func hasExtra(extra []byte, tag uint16) bool {
+	return len(removeExtra(extra, tag)) != len(extra)
+}
+
+// removeExtra returns a copy of the extra fields without the blocks with the
+// given tag.
+func removeExtra(extra []byte, tag uint16) []byte {
+	out := []byte{}
+
+	for len(extra) >= 4 {
+		size := 4 + int(binary.LittleEndian.Uint16(extra[2:4]))
+		if size > len(extra) {
+			break
+		}
+
+		if binary.LittleEndian.Uint16(extra[:2]) != tag {
+			out = append(out, extra[:size]...)
+		}
+
+		extra = extra[size:]
+	}
+
+	return out
+}
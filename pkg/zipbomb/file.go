@@ -0,0 +1,66 @@
+package zipbomb
+
+import (
+	"io"
+	"io/fs"
+	"time"
+)
+
+type FileOptions struct {
+	Method           uint16 // Store, Deflate or BZip2
+	CompressionLevel int    // Deflate [-2,9]
+	FileMode         fs.FileMode
+	Modified         time.Time
+	Comment          string
+	DataDescriptor   DataDescriptor
+
+	// Extra is appended to the extra fields of the entry.
+	Extra []byte
+
+	EncryptionOptions
+}
+
+// AddFile adds an ordinary, well-formed file with the content of r. r is
+// read once, up to its end.
+func (zb *ZipBomb) AddFile(name string, r io.Reader, optFns ...func(o *FileOptions)) error {
+	opts := FileOptions{
+		CompressionLevel: 5,
+		Method:           Deflate,
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	// r is compressed while it is read, its length is not known in advance
+	k, err := zb.newKernel(name, NewReaderKernel(r, -1), opts.Method, opts.CompressionLevel)
+	if err != nil {
+		return err
+	}
+
+	lfh := k.LocalFileHeader()
+	lfh.Comment = opts.Comment
+
+	if opts.FileMode != 0 {
+		lfh.SetMode(opts.FileMode)
+	}
+
+	if !opts.Modified.IsZero() {
+		lfh.ModifiedDate, lfh.ModifiedTime = timeToMsDosTime(opts.Modified)
+	}
+
+	lfh.Extra = append(lfh.Extra, opts.Extra...)
+
+	files := []fileRecord{
+		{
+			header:         lfh,
+			data:           k.Data(),
+			dataDescriptor: opts.DataDescriptor,
+			encryption:     opts.EncryptionOptions,
+		},
+	}
+
+	zb.uncompressedSize = zb.uncompressedSize + int64(k.UncompressedSize())
+
+	return zb.writeFiles(files)
+}
@@ -34,6 +34,9 @@ type fileHeader struct {
 
 	extraLengthExcess   uint16
 	extraFieldEscapeTag uint16
+
+	forceZip64 bool
+	zip64Extra Zip64Extra
 }
 
 func newFileHeader(compressedSize, uncompressedSize uint64, crc32 uint32, name string, method uint16) *fileHeader {
@@ -44,45 +47,24 @@ func newFileHeader(compressedSize, uncompressedSize uint64, crc32 uint32, name s
 		UncompressedSize64: uncompressedSize,
 		CRC32:              crc32,
 		Name:               name,
-		Method:             Deflate,
+		Method:             method,
 		ModifiedTime:       ftime,
 		ModifiedDate:       fdate,
 	}
 
-	var zipVersion uint16
-
 	switch method {
+	case Store:
+		lfh.ReaderVersion = zipVersion10
 	case Deflate:
-		if lfh.IsZip64() {
-			zipVersion = zipVersion45
-		} else {
-			zipVersion = zipVersion20
-		}
+		lfh.ReaderVersion = zipVersion20
 	case BZip2:
-		zipVersion = zipVersion46
-	}
-
-	if lfh.IsZip64() {
-		lfh.CompressedSize = uint32max
-		lfh.UncompressedSize = uint32max
-		lfh.ReaderVersion = zipVersion
-
-		// append a zip64 extra block to Extra
-		var buf [20]byte // 2x uint16 + 2x uint64
-		eb := writeBuf(buf[:])
-		eb.uint16(zip64ExtraID)
-		eb.uint16(16) // size = 2x uint64
-		eb.uint64(lfh.UncompressedSize64)
-		eb.uint64(lfh.CompressedSize64)
-		lfh.Extra = append(lfh.Extra, buf[:]...)
-	} else {
-		lfh.CompressedSize = uint32(lfh.CompressedSize64)
-		lfh.UncompressedSize = uint32(lfh.UncompressedSize64)
-		lfh.ReaderVersion = zipVersion
+		lfh.ReaderVersion = zipVersion46
 	}
 
 	lfh.CreatorVersion = (0 << 8) | lfh.ReaderVersion
 
+	lfh.updateZip64()
+
 	return lfh
 }
 
@@ -110,9 +92,10 @@ func (h *fileHeader) ExtraFieldEscapeTag() uint16 {
 // 	return h.CompressedSize64
 // }
 
-// IsZip64 reports whether the file size exceeds the 32 bit limit
+// IsZip64 reports whether the file size exceeds the 32 bit limit or zip64 is
+// forced.
 func (h *fileHeader) IsZip64() bool {
-	return h.CompressedSize64 >= uint32max || h.UncompressedSize64 >= uint32max
+	return h.forceZip64 || h.CompressedSize64 >= uint32max || h.UncompressedSize64 >= uint32max
 }
 
 // SetMode changes the permission and mode bits for the FileHeader.
@@ -141,14 +124,14 @@ func (h *fileHeader) MarshalBinary() ([]byte, error) {
 		return nil, errLongExtra
 	}
 
-	var extra []byte
+	extra := h.Extra
 
 	if h.extraFieldEscapeTag != 0 {
 		var buf [4]byte
 		eb := writeBuf(buf[:])
 		eb.uint16(h.extraFieldEscapeTag)
 		eb.uint16(h.extraLengthExcess)
-		extra = append(h.Extra, buf[:]...)
+		extra = append(append([]byte{}, h.Extra...), buf[:]...)
 	}
 
 	var buf [fileHeaderLen]byte
@@ -161,8 +144,8 @@ func (h *fileHeader) MarshalBinary() ([]byte, error) {
 	b.uint16(h.ModifiedDate)
 	b.uint32(h.CRC32)
 
-	b.uint32(uint32(min64(h.CompressedSize64, uint32max)))
-	b.uint32(uint32(min64(h.UncompressedSize64, uint32max)))
+	b.uint32(h.CompressedSize)
+	b.uint32(h.UncompressedSize)
 
 	b.uint16(uint16(len(h.Name)))
 	b.uint16(uint16(len(extra)) + h.extraLengthExcess)
@@ -226,11 +209,3 @@ func fileModeToUnixMode(mode fs.FileMode) uint32 {
 
 	return m | uint32(mode&0777)
 }
-
-func min64(x, y uint64) uint64 {
-	if x < y {
-		return x
-	}
-
-	return y
-}
@@ -4,31 +4,89 @@ import (
 	"bytes"
 	"compress/flate"
 	"hash/crc32"
+	"io"
 
 	"github.com/hupe1980/zipbomb/pkg/bzip2"
 )
 
 type kernel struct {
-	rawBytes        []byte
-	compressedBytes []byte
-	crc32           uint32
-	lfh             *fileHeader
-	name            string
+	data           io.WriterTo
+	compressedSize uint64
+	crc32          uint32
+	size           uint64
+	lfh            *fileHeader
+	name           string
 }
 
-func newKernel(name string, data []byte, method uint16, compressionLevel int) (*kernel, error) {
-	compressedBytes, err := CompressKernel(data, method, compressionLevel)
+// newKernel compresses src in a single streaming pass. Only the compressed
+// bytes, the CRC-32 and the length are kept. Repeat kernels are not read at
+// all: their CRC-32 is combined and their compressed data is generated while
+// it is written. Other deflate kernels are compressed on Options.Workers
+// goroutines.
+func (zb *ZipBomb) newKernel(name string, src Kernel, method uint16, compressionLevel int) (*kernel, error) {
+	if src == nil {
+		src = BytesKernel(nil)
+	}
+
+	if rk, ok := src.(RepeatKernel); ok {
+		if rk.Repeats < 0 {
+			return nil, errRepeats
+		}
+
+		if data := repeatData(rk, method, compressionLevel); data != nil {
+			k := &kernel{
+				data:           data,
+				compressedSize: data.Len(),
+				crc32:          repeatCRC32(rk),
+				size:           uint64(rk.Len()),
+				name:           name,
+			}
+
+			k.lfh = newFileHeader(k.CompressedSize(), k.UncompressedSize(), k.CRC32(), name, method)
+
+			return k, nil
+		}
+	}
+
+	r, err := src.Open()
 	if err != nil {
 		return nil, err
 	}
 
-	crc32 := crc32.ChecksumIEEE(data)
+	defer r.Close()
+
+	var (
+		crc    uint32
+		size   int64
+		buffer = new(bytes.Buffer)
+	)
+
+	if method == Deflate && zb.opts.Workers > 1 {
+		if crc, size, err = compressParallel(buffer, r, compressionLevel, zb.opts.Workers); err != nil {
+			return nil, err
+		}
+	} else {
+		h := crc32.NewIEEE()
+		cr := &countReader{r: io.TeeReader(r, h)}
+
+		if err := compress(buffer, cr, method, compressionLevel); err != nil {
+			return nil, err
+		}
+
+		crc, size = h.Sum32(), cr.count
+	}
+
+	// a negative length is not known in advance
+	if src.Len() >= 0 && size != src.Len() {
+		return nil, errKernelLength
+	}
 
 	k := &kernel{
-		rawBytes:        data,
-		compressedBytes: compressedBytes,
-		crc32:           crc32,
-		name:            name,
+		data:           byteData(buffer.Bytes()),
+		compressedSize: uint64(buffer.Len()),
+		crc32:          crc,
+		size:           uint64(size),
+		name:           name,
 	}
 
 	k.lfh = newFileHeader(k.CompressedSize(), k.UncompressedSize(), k.CRC32(), name, method)
@@ -49,56 +107,147 @@ func (k *kernel) CRC32() uint32 {
 }
 
 func (k *kernel) UncompressedSize() uint64 {
-	return uint64(len(k.rawBytes))
+	return k.size
 }
 
 func (k *kernel) CompressedSize() uint64 {
-	return uint64(len(k.compressedBytes))
-}
-
-func (k *kernel) Bytes() []byte {
-	return k.rawBytes
+	return k.compressedSize
 }
 
-func (k *kernel) CompressedBytes() []byte {
-	return k.compressedBytes
+// Data returns the compressed data, which can be written any number of times.
+func (k *kernel) Data() io.WriterTo {
+	return k.data
 }
 
 func (k *kernel) Name() string {
 	return k.name
 }
 
-func CompressKernel(data []byte, method uint16, level int) ([]byte, error) {
+type CompressOptions struct {
+	// Workers is the number of goroutines that deflate the data. With more
+	// than one worker the data is compressed in independent chunks.
+	Workers int
+}
+
+func CompressKernel(data []byte, method uint16, level int, optFns ...func(o *CompressOptions)) ([]byte, error) {
+	opts := CompressOptions{Workers: 1}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
 	buffer := new(bytes.Buffer)
 
+	if method == Deflate && opts.Workers > 1 {
+		if _, _, err := compressParallel(buffer, bytes.NewReader(data), level, opts.Workers); err != nil {
+			return nil, err
+		}
+
+		return buffer.Bytes(), nil
+	}
+
+	if err := compress(buffer, bytes.NewReader(data), method, level); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// compress writes the content of r compressed with method to w.
+func compress(w io.Writer, r io.Reader, method uint16, level int) error {
 	switch method {
+	case Store:
+		_, err := io.Copy(w, r)
+
+		return err
 	case Deflate:
-		fw, err := flate.NewWriter(buffer, level)
+		fw, err := flate.NewWriter(w, level)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if _, err := fw.Write(data); err != nil {
-			return nil, err
+		if _, err := io.Copy(fw, r); err != nil {
+			return err
 		}
 
-		if err := fw.Close(); err != nil {
-			return nil, err
-		}
+		return fw.Close()
 	case BZip2:
-		fw, err := bzip2.NewWriter(buffer, level)
+		fw, err := bzip2.NewWriter(w, level)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if _, err := fw.Write(data); err != nil {
-			return nil, err
+		if _, err := io.Copy(fw, r); err != nil {
+			return err
 		}
 
-		if err := fw.Close(); err != nil {
-			return nil, err
+		return fw.Close()
+	default:
+		return errMethod
+	}
+}
+
+// repeatData returns the compressed data of a repeat kernel that is generated
+// while it is written, or nil if the method and level need the regular
+// compressor.
+func repeatData(k RepeatKernel, method uint16, level int) sizedData {
+	switch method {
+	case Store:
+		return storedData{k}
+	case Deflate:
+		if level == flate.NoCompression || level == flate.HuffmanOnly || !canRepeatDeflate(k) {
+			return nil
 		}
+
+		return newRepeatDeflate(k)
+	default:
+		return nil
 	}
+}
 
-	return buffer.Bytes(), nil
+type sizedData interface {
+	io.WriterTo
+	Len() uint64
+}
+
+// byteData is data held in memory. Unlike bytes.Reader it can be written
+// more than once.
+type byteData []byte
+
+func (d byteData) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(d)
+
+	return int64(n), err
+}
+
+// storedData is the uncompressed content of a repeat kernel.
+type storedData struct {
+	k RepeatKernel
+}
+
+func (d storedData) WriteTo(w io.Writer) (int64, error) {
+	r, err := d.k.Open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer r.Close()
+
+	return io.Copy(w, r)
+}
+
+func (d storedData) Len() uint64 {
+	return uint64(d.k.Len())
+}
+
+type countReader struct {
+	r     io.Reader
+	count int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.count += int64(n)
+
+	return n, err
 }
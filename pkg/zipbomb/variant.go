@@ -0,0 +1,112 @@
+package zipbomb
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Variant is a spelling of a path traversal filename.
+type Variant string
+
+// Path traversal variants.
+const (
+	VariantPlain         Variant = "plain"          // ../../file
+	VariantBackslash     Variant = "backslash"      // ..\..\file
+	VariantMixed         Variant = "mixed"          // ..\../file
+	VariantAbsolute      Variant = "absolute"       // /file
+	VariantDrive         Variant = "drive"          // C:\file
+	VariantUNC           Variant = "unc"            // \\127.0.0.1\C$\file
+	VariantTrailingDot   Variant = "trailing-dot"   // ../../file.
+	VariantTrailingSpace Variant = "trailing-space" // ../../file<space>
+	VariantUnicodePath   Variant = "unicode-path"   // file, ../../file in the Unicode Path extra field
+)
+
+// Variants lists all path traversal variants.
+var Variants = []Variant{
+	VariantPlain,
+	VariantBackslash,
+	VariantMixed,
+	VariantAbsolute,
+	VariantDrive,
+	VariantUNC,
+	VariantTrailingDot,
+	VariantTrailingSpace,
+	VariantUnicodePath,
+}
+
+// ParseVariants returns the variants with the given names. The name "all"
+// selects all variants.
+func ParseVariants(names []string) ([]Variant, error) {
+	variants := []Variant{}
+
+	for _, name := range names {
+		if name == "all" {
+			return Variants, nil
+		}
+
+		found := false
+
+		for _, v := range Variants {
+			if string(v) == name {
+				variants = append(variants, v)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown variant %q", name)
+		}
+	}
+
+	return variants, nil
+}
+
+// ZipSlipName is the name of a zip-slip entry.
+type ZipSlipName struct {
+	// Name is the raw name of the entry.
+	Name string
+
+	// UnicodePath is the name in the Info-ZIP Unicode Path extra field,
+	// if any.
+	UnicodePath string
+}
+
+// Apply spells the filename in the variant.
+func (v Variant) Apply(filename string) ZipSlipName {
+	absolute := path.Clean("/" + strings.ReplaceAll(filename, `\`, "/"))
+
+	switch v {
+	case VariantBackslash:
+		return ZipSlipName{Name: strings.ReplaceAll(filename, "/", `\`)}
+	case VariantMixed:
+		parts := strings.Split(filename, "/")
+		name := parts[0]
+
+		for i, part := range parts[1:] {
+			if i%2 == 0 {
+				name += `\` + part
+			} else {
+				name += "/" + part
+			}
+		}
+
+		return ZipSlipName{Name: name}
+	case VariantAbsolute:
+		return ZipSlipName{Name: absolute}
+	case VariantDrive:
+		return ZipSlipName{Name: `C:` + strings.ReplaceAll(absolute, "/", `\`)}
+	case VariantUNC:
+		return ZipSlipName{Name: `\\127.0.0.1\C$` + strings.ReplaceAll(absolute, "/", `\`)}
+	case VariantTrailingDot:
+		return ZipSlipName{Name: filename + "."}
+	case VariantTrailingSpace:
+		return ZipSlipName{Name: filename + " "}
+	case VariantUnicodePath:
+		return ZipSlipName{Name: path.Base(absolute), UnicodePath: filename}
+	default:
+		return ZipSlipName{Name: filename}
+	}
+}
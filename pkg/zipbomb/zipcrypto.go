@@ -0,0 +1,133 @@
+package zipbomb
+
+import (
+	"crypto/rand"
+	"hash/crc32"
+	"io"
+)
+
+// zipCryptoHeaderLen is the length of the encryption header that precedes
+// the encrypted data.
+const zipCryptoHeaderLen = 12
+
+var crcTable = crc32.MakeTable(crc32.IEEE)
+
+// zipCrypto is the traditional PKWARE encryption. See APPNOTE.TXT 6.1.
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+func newZipCrypto(password string) *zipCrypto {
+	z := &zipCrypto{keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+
+	for i := 0; i < len(password); i++ {
+		z.update(password[i])
+	}
+
+	return z
+}
+
+func (z *zipCrypto) update(b byte) {
+	z.keys[0] = crcUpdate(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+z.keys[0]&0xff)*134775813 + 1
+	z.keys[2] = crcUpdate(z.keys[2], byte(z.keys[1]>>24))
+}
+
+func (z *zipCrypto) streamByte() byte {
+	t := z.keys[2] | 2
+
+	return byte((t * (t ^ 1)) >> 8)
+}
+
+// encrypt encrypts p in place.
+func (z *zipCrypto) encrypt(p []byte) {
+	for i, b := range p {
+		p[i] = b ^ z.streamByte()
+		z.update(b)
+	}
+}
+
+func crcUpdate(crc uint32, b byte) uint32 {
+	return crcTable[byte(crc)^b] ^ crc>>8
+}
+
+// zipCryptoData is file data that is encrypted while it is written. Every
+// write uses a new random encryption header.
+type zipCryptoData struct {
+	data     io.WriterTo
+	password string
+	check    byte // last byte of the encryption header
+}
+
+func (d *zipCryptoData) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, zipCryptoHeaderLen)
+	if _, err := rand.Read(header[:zipCryptoHeaderLen-1]); err != nil {
+		return 0, err
+	}
+
+	header[zipCryptoHeaderLen-1] = d.check
+
+	z := newZipCrypto(d.password)
+	z.encrypt(header)
+
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	if d.data == nil {
+		return int64(n), nil
+	}
+
+	m, err := d.data.WriteTo(&zipCryptoWriter{w: w, z: z})
+
+	return int64(n) + m, err
+}
+
+type zipCryptoWriter struct {
+	w   io.Writer
+	z   *zipCrypto
+	buf []byte
+}
+
+func (w *zipCryptoWriter) Write(p []byte) (int, error) {
+	if cap(w.buf) < len(p) {
+		w.buf = make([]byte, len(p))
+	}
+
+	buf := w.buf[:len(p)]
+	copy(buf, p)
+	w.z.encrypt(buf)
+
+	return w.w.Write(buf)
+}
+
+// withZipCrypto returns copies of the local and the central header of an
+// entry that is encrypted with the password, and its encrypted data.
+func withZipCrypto(header, central *fileHeader, data io.WriterTo, password string, descriptor bool) (*fileHeader, *fileHeader, io.WriterTo) {
+	encrypted := func(h *fileHeader) *fileHeader {
+		c := *h
+		c.Flags |= flagEncrypted
+		c.CompressedSize64 += zipCryptoHeaderLen
+		c.Extra = append([]byte{}, h.Extra...)
+		c.updateZip64()
+
+		return &c
+	}
+
+	lfh := encrypted(header)
+
+	cdh := lfh
+	if central != header {
+		cdh = encrypted(central)
+	}
+
+	// the header is checked against the CRC-32, or the modification time if
+	// the CRC-32 follows in a data descriptor
+	check := byte(header.CRC32 >> 24)
+	if descriptor {
+		check = byte(header.ModifiedTime >> 8)
+	}
+
+	return lfh, cdh, &zipCryptoData{data: data, password: password, check: check}
+}